@@ -0,0 +1,380 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrParseDurationText is an error that occurs when a string cannot be parsed
+// as a duration.
+var ErrParseDurationText = errors.New(`ErrParseDurationText: should be formatted as "[[HH:]MM:]SS[.mmm]" or "1h 2m 3s"`)
+
+// verboseDuration matches durations like "1h 2m 3s", "1h02m03.5s" or "3s 45ms"
+var verboseDuration = regexp.MustCompile(`^(?:(\d+)\s*h)?\s*(?:(\d+)\s*m(?:in)?)?\s*(?:(\d+(?:\.\d+)?)\s*s)?\s*(?:(\d+)\s*ms)?$`)
+
+// ParseDuration parses a duration as it is usually written by runners, either
+// in the clock form "[[HH:]MM:]SS[.mmm]" (e.g. "1:02:03.456" or "59.5") or in
+// the verbose form "1h 2m 3s" (each unit is optional, "ms" is understood as
+// well). Fractions of seconds are parsed exactly, up to nanoseconds.
+func ParseDuration(s string) (Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if len(s) == 0 {
+		return Duration{}, ErrParseDurationText
+	}
+
+	if strings.ContainsAny(s, "hms") {
+		return parseVerboseDuration(s)
+	}
+
+	return parseClockDuration(s)
+}
+
+// parseClockDuration parses "[[HH:]MM:]SS[.mmm]".
+func parseClockDuration(s string) (Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return Duration{}, ErrParseDurationText
+	}
+
+	last := len(parts) - 1
+
+	seconds, err := parseSeconds(parts[last])
+	if err != nil {
+		return Duration{}, err
+	}
+
+	// seconds and minutes must not overflow if a larger unit is given
+	if last > 0 && seconds >= time.Minute {
+		return Duration{}, ErrParseDurationText
+	}
+
+	result := seconds
+	units := []time.Duration{time.Minute, time.Hour}
+
+	for idx := last - 1; idx >= 0; idx-- {
+		value, err := strconv.Atoi(parts[idx])
+		if err != nil || value < 0 || parts[idx][0] == '+' {
+			return Duration{}, ErrParseDurationText
+		}
+
+		unit := units[last-1-idx]
+
+		if idx > 0 && unit == time.Minute && value >= 60 {
+			return Duration{}, ErrParseDurationText
+		}
+
+		result += time.Duration(value) * unit
+	}
+
+	return Duration{result}, nil
+}
+
+// parseVerboseDuration parses "1h 2m 3s".
+func parseVerboseDuration(s string) (Duration, error) {
+	match := verboseDuration.FindStringSubmatch(s)
+	if match == nil || len(strings.Join(match[1:], "")) == 0 {
+		return Duration{}, ErrParseDurationText
+	}
+
+	var result time.Duration
+
+	if len(match[1]) > 0 {
+		hours, _ := strconv.Atoi(match[1])
+		result += time.Duration(hours) * time.Hour
+	}
+
+	if len(match[2]) > 0 {
+		minutes, _ := strconv.Atoi(match[2])
+		result += time.Duration(minutes) * time.Minute
+	}
+
+	if len(match[3]) > 0 {
+		seconds, err := parseSeconds(match[3])
+		if err != nil {
+			return Duration{}, err
+		}
+
+		result += seconds
+	}
+
+	if len(match[4]) > 0 {
+		millis, _ := strconv.Atoi(match[4])
+		result += time.Duration(millis) * time.Millisecond
+	}
+
+	return Duration{result}, nil
+}
+
+// parseSeconds parses "SS[.fraction]" without going through floats.
+func parseSeconds(s string) (time.Duration, error) {
+	whole, fraction := s, ""
+
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		whole, fraction = s[:dot], s[dot+1:]
+	}
+
+	if len(whole) == 0 || len(fraction) > 9 || (len(fraction) == 0 && strings.HasSuffix(s, ".")) {
+		return 0, ErrParseDurationText
+	}
+
+	seconds, err := strconv.Atoi(whole)
+	if err != nil || seconds < 0 || whole[0] == '+' {
+		return 0, ErrParseDurationText
+	}
+
+	result := time.Duration(seconds) * time.Second
+
+	if len(fraction) > 0 {
+		nanos, err := strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+		if err != nil || fraction[0] == '+' || fraction[0] == '-' {
+			return 0, ErrParseDurationText
+		}
+
+		result += time.Duration(nanos)
+	}
+
+	return result, nil
+}
+
+// Time returns the run's time, measured with the given timing method. If the
+// run has no such time or if method is empty, the game's rules are used as a
+// fallback: first the game's default time, then all other times the game
+// uses, in the game's order. If none of them is available, the primary time
+// is returned. The game is only looked at if a fallback is needed; if it has
+// not been embedded, it is fetched (one additional request).
+func (r *Run) Time(method TimingMethod) (*Duration, *Error) {
+	if duration := r.timeFor(method); duration != nil {
+		return duration, nil
+	}
+
+	if r.GameData == nil {
+		return r.timeByRules(method, nil), nil
+	}
+
+	game, err := r.Game(NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.timeByRules(method, game), nil
+}
+
+// timeByRules implements the fallback logic of Time(), with the game being
+// optional.
+func (r *Run) timeByRules(method TimingMethod, game *Game) *Duration {
+	candidates := []TimingMethod{method}
+
+	if game != nil {
+		candidates = append(candidates, game.Ruleset.DefaultTime)
+		candidates = append(candidates, game.Ruleset.RunTimes...)
+	}
+
+	for _, candidate := range candidates {
+		if duration := r.timeFor(candidate); duration != nil {
+			return duration
+		}
+	}
+
+	return r.Times.Primary
+}
+
+// DurationStyle selects how a duration is formatted.
+type DurationStyle int
+
+const (
+	// ClockStyle formats like a stopwatch, e.g. "1:02:03.045".
+	ClockStyle DurationStyle = iota
+
+	// VerboseStyle names every unit, e.g. "1h 02m 03s 045ms".
+	VerboseStyle
+
+	// CompactStyle names every unit but omits zero units and spaces, e.g.
+	// "1h2m3.045s".
+	CompactStyle
+)
+
+// AutoPrecision shows milliseconds only if the duration has any.
+const AutoPrecision = -1
+
+// Format returns a human readable time in the form of "[[H:]MM:]SS[.mmm]".
+// Milliseconds are only shown if the duration has any.
+func (d *Duration) Format() string {
+	return d.FormatStyle(ClockStyle, AutoPrecision)
+}
+
+// FormatStyle formats the duration in the given style, showing the given
+// number of fractional digits (0 to 9). The duration is rounded to the
+// nearest millisecond (unless more digits are requested) and then truncated
+// to the precision, like speedrun.com does (59.999 with a precision of 0 is
+// "59"). In VerboseStyle, fractions are always shown as milliseconds.
+func (d *Duration) FormatStyle(style DurationStyle, precision int) string {
+	total := d.Duration
+	negative := total < 0
+
+	if negative {
+		total = -total
+	}
+
+	// get rid of floating point noise, unless more precision is requested
+	if precision <= 3 {
+		total = (total + time.Millisecond/2) / time.Millisecond * time.Millisecond
+	}
+
+	if precision == AutoPrecision {
+		precision = 0
+		if total%time.Second != 0 {
+			precision = 3
+		}
+	}
+
+	if precision < 0 {
+		precision = 0
+	} else if precision > 9 {
+		precision = 9
+	}
+
+	if style == VerboseStyle && precision > 3 {
+		precision = 3
+	}
+
+	unit := time.Duration(1)
+	for i := precision; i < 9; i++ {
+		unit *= 10
+	}
+
+	total = total / unit * unit
+
+	hours := int64(total / time.Hour)
+	minutes := int64(total / time.Minute % 60)
+	seconds := int64(total / time.Second % 60)
+	fraction := ""
+
+	if precision > 0 {
+		fraction = strconv.FormatInt(int64(total%time.Second/unit), 10)
+		fraction = strings.Repeat("0", precision-len(fraction)) + fraction
+	}
+
+	var formatted string
+
+	switch style {
+	case VerboseStyle:
+		formatted = formatUnits([]int64{hours, minutes, seconds}, []string{"h", "m", "s"}, " ")
+
+		if precision > 0 {
+			millis := fraction + strings.Repeat("0", 3-precision)
+
+			if total < time.Second {
+				formatted = strings.TrimLeft(millis, "0")
+				if len(formatted) == 0 {
+					formatted = "0"
+				}
+			} else {
+				formatted += " " + millis
+			}
+
+			formatted += "ms"
+		}
+
+	case CompactStyle:
+		var parts []string
+
+		if hours > 0 {
+			parts = append(parts, strconv.FormatInt(hours, 10)+"h")
+		}
+
+		if minutes > 0 {
+			parts = append(parts, strconv.FormatInt(minutes, 10)+"m")
+		}
+
+		fraction = strings.TrimRight(fraction, "0")
+
+		if seconds > 0 || len(fraction) > 0 || len(parts) == 0 {
+			secs := strconv.FormatInt(seconds, 10)
+
+			if len(fraction) > 0 {
+				secs += "." + fraction
+			}
+
+			parts = append(parts, secs+"s")
+		}
+
+		formatted = strings.Join(parts, "")
+
+	default:
+		formatted = formatUnits([]int64{hours, minutes, seconds}, []string{"", "", ""}, ":")
+
+		if precision > 0 {
+			formatted += "." + fraction
+		}
+	}
+
+	if negative {
+		formatted = "-" + formatted
+	}
+
+	return formatted
+}
+
+// formatUnits joins hours, minutes and seconds, leaving out leading zero
+// units (seconds are always shown). All but the first unit are padded to two
+// digits.
+func formatUnits(values []int64, suffixes []string, separator string) string {
+	var parts []string
+
+	for idx, value := range values {
+		if len(parts) == 0 && value == 0 && idx < len(values)-1 {
+			continue
+		}
+
+		part := strconv.FormatInt(value, 10)
+		if len(parts) > 0 && value < 10 {
+			part = "0" + part
+		}
+
+		parts = append(parts, part+suffixes[idx])
+	}
+
+	return strings.Join(parts, separator)
+}
+
+// FormatTime formats a duration the way the game displays times: with
+// milliseconds if the game's ruleset says so, without them otherwise.
+func (g *Game) FormatTime(d *Duration, style DurationStyle) string {
+	if g.Ruleset.ShowMilliseconds {
+		return d.FormatStyle(style, 3)
+	}
+
+	return d.FormatStyle(style, 0)
+}
+
+// FormatTime formats the run's time (as returned by Time()) the way its game
+// displays times. The game is fetched if it has not been embedded.
+func (r *Run) FormatTime(method TimingMethod, style DurationStyle) (string, *Error) {
+	var game *Game
+
+	if r.GameData != nil {
+		fetched, err := r.Game(NoEmbeds)
+		if err != nil {
+			return "", err
+		}
+
+		game = fetched
+	}
+
+	duration := r.timeByRules(method, game)
+	if duration == nil {
+		return "", nil
+	}
+
+	if game == nil {
+		return duration.FormatStyle(style, AutoPrecision), nil
+	}
+
+	return game.FormatTime(duration, style), nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDurations(t *testing.T) {
+	Convey("Parsing durations", t, func() {
+		valid := map[string]time.Duration{
+			"1:02:03.456":     time.Hour + 2*time.Minute + 3456*time.Millisecond,
+			"02:03":           2*time.Minute + 3*time.Second,
+			"59.5":            59500 * time.Millisecond,
+			"75":              75 * time.Second,
+			"90:00":           90 * time.Minute,
+			"27:59:59.999999": 27*time.Hour + 59*time.Minute + 59*time.Second + 999999*time.Microsecond,
+			"1h 2m 3s":        time.Hour + 2*time.Minute + 3*time.Second,
+			"1h02m03.045s":    time.Hour + 2*time.Minute + 3045*time.Millisecond,
+			"2m":              2 * time.Minute,
+			"3s 45ms":         3045 * time.Millisecond,
+			" 4H ":            4 * time.Hour,
+		}
+
+		for input, expected := range valid {
+			parsed, err := ParseDuration(input)
+			So(err, ShouldBeNil)
+			So(parsed.Duration, ShouldEqual, expected)
+		}
+
+		invalid := []string{"", "1:2:3:4", "1:60", "1:60:00", "-5", "1.", "abc", "1h 2x", "1.0000000001", "h"}
+
+		for _, input := range invalid {
+			_, err := ParseDuration(input)
+			So(err, ShouldEqual, ErrParseDurationText)
+		}
+	})
+
+	Convey("Picking run times", t, func() {
+		run := &Run{}
+		run.Times.Primary = &Duration{3 * time.Second}
+		run.Times.Realtime = &Duration{3 * time.Second}
+		run.Times.IngameTime = &Duration{2 * time.Second}
+
+		game := &Game{}
+		game.Ruleset.DefaultTime = TimingRealtimeWithoutLoads
+		game.Ruleset.RunTimes = []TimingMethod{TimingRealtimeWithoutLoads, TimingIngameTime, TimingRealtime}
+
+		So(run.timeByRules(TimingRealtime, game).Duration, ShouldEqual, 3*time.Second)
+		So(run.timeByRules(TimingRealtimeWithoutLoads, game).Duration, ShouldEqual, 2*time.Second)
+		So(run.timeByRules("", game).Duration, ShouldEqual, 2*time.Second)
+		So(run.timeByRules(TimingRealtimeWithoutLoads, nil).Duration, ShouldEqual, 3*time.Second)
+
+		duration, err := run.Time(TimingIngameTime)
+		So(err, ShouldBeNil)
+		So(duration.Duration, ShouldEqual, 2*time.Second)
+	})
+}
+
+func TestDurationFormatting(t *testing.T) {
+	d := func(value time.Duration) *Duration {
+		return &Duration{value}
+	}
+
+	long := d(time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond)
+
+	Convey("Formatting durations like a clock", t, func() {
+		So(long.Format(), ShouldEqual, "1:02:03.045")
+		So(d(50*time.Millisecond).Format(), ShouldEqual, "0.050")
+		So(d(5*time.Minute+55*time.Second).Format(), ShouldEqual, "5:55")
+		So(d(10*time.Second).Format(), ShouldEqual, "10")
+		So(d(0).Format(), ShouldEqual, "0")
+		So(d(2164889999*time.Microsecond).Format(), ShouldEqual, "36:04.890")
+
+		So(long.FormatStyle(ClockStyle, 0), ShouldEqual, "1:02:03")
+		So(long.FormatStyle(ClockStyle, 1), ShouldEqual, "1:02:03.0")
+		So(long.FormatStyle(ClockStyle, 2), ShouldEqual, "1:02:03.04")
+		So(d(1500*time.Microsecond).FormatStyle(ClockStyle, 6), ShouldEqual, "0.001500")
+		So(d(59999*time.Millisecond).FormatStyle(ClockStyle, 0), ShouldEqual, "59")
+		So(d(-time.Second).Format(), ShouldEqual, "-1")
+	})
+
+	Convey("Formatting durations verbosely", t, func() {
+		So(long.FormatStyle(VerboseStyle, 3), ShouldEqual, "1h 02m 03s 045ms")
+		So(long.FormatStyle(VerboseStyle, 0), ShouldEqual, "1h 02m 03s")
+		So(d(3*time.Second).FormatStyle(VerboseStyle, AutoPrecision), ShouldEqual, "3s")
+		So(d(45*time.Millisecond).FormatStyle(VerboseStyle, 3), ShouldEqual, "45ms")
+		So(d(0).FormatStyle(VerboseStyle, 0), ShouldEqual, "0s")
+	})
+
+	Convey("Formatting durations compactly", t, func() {
+		So(long.FormatStyle(CompactStyle, 3), ShouldEqual, "1h2m3.045s")
+		So(d(time.Hour+5*time.Second).FormatStyle(CompactStyle, 3), ShouldEqual, "1h5s")
+		So(d(time.Hour).FormatStyle(CompactStyle, 0), ShouldEqual, "1h")
+		So(d(0).FormatStyle(CompactStyle, 0), ShouldEqual, "0s")
+	})
+
+	Convey("Formatting run times by the game's rules", t, func() {
+		game := &Game{}
+		So(game.FormatTime(long, ClockStyle), ShouldEqual, "1:02:03")
+
+		game.Ruleset.ShowMilliseconds = true
+		So(game.FormatTime(long, VerboseStyle), ShouldEqual, "1h 02m 03s 045ms")
+
+		run := &Run{}
+		run.Times.Primary = long
+
+		formatted, err := run.FormatTime("", ClockStyle)
+		So(err, ShouldBeNil)
+		So(formatted, ShouldEqual, "1:02:03.045")
+	})
+}
+
+func TestDurationJSON(t *testing.T) {
+	Convey("Decoding times exactly", t, func() {
+		inputs := map[string]time.Duration{
+			"355":            355 * time.Second,
+			"2164.89":        2164890 * time.Millisecond,
+			"86399.999":      86399999 * time.Millisecond,
+			"123456.001":     123456001 * time.Millisecond,
+			"0.1234567894":   123456789,
+			"1.5e3":          1500 * time.Second,
+			"360000.000001":  360000000001 * time.Microsecond,
+			"-1.25":          -1250 * time.Millisecond,
+			"0.0000000005":   1,
+			"-0.0000000005":  -1,
+			"1000000000000":  0, // overflow
+			"not-a-duration": 0,
+		}
+
+		for input, expected := range inputs {
+			d := Duration{}
+			err := json.Unmarshal([]byte(input), &d)
+
+			if expected == 0 {
+				So(err, ShouldNotBeNil)
+			} else {
+				So(err, ShouldBeNil)
+				So(d.Duration, ShouldEqual, expected)
+			}
+		}
+
+		So(json.Unmarshal([]byte("1/3"), &Duration{}), ShouldNotBeNil)
+	})
+
+	Convey("Encoding times exactly", t, func() {
+		for _, value := range []time.Duration{0, 355 * time.Second, 2164890 * time.Millisecond, 123456001 * time.Millisecond, 1, -1250 * time.Millisecond} {
+			encoded, err := json.Marshal(Duration{value})
+			So(err, ShouldBeNil)
+
+			decoded := Duration{}
+			So(json.Unmarshal(encoded, &decoded), ShouldBeNil)
+			So(decoded.Duration, ShouldEqual, value)
+		}
+
+		encoded, _ := json.Marshal(Duration{2164890 * time.Millisecond})
+		So(string(encoded), ShouldEqual, "2164.89")
+
+		encoded, _ = json.Marshal(Duration{355 * time.Second})
+		So(string(encoded), ShouldEqual, "355")
+	})
+
+	Convey("Comparing times", t, func() {
+		a := &Duration{time.Second}
+		b := &Duration{2 * time.Second}
+
+		So(a.Compare(*b), ShouldEqual, -1)
+		So(b.Compare(*a), ShouldEqual, 1)
+		So(a.Compare(*a), ShouldEqual, 0)
+		So(a.Less(*b), ShouldBeTrue)
+		So(a.Equal(Duration{time.Second}), ShouldBeTrue)
+
+		So(CompareTimes(a, nil), ShouldEqual, -1)
+		So(CompareTimes(nil, a), ShouldEqual, 1)
+		So(CompareTimes(nil, nil), ShouldEqual, 0)
+		So(CompareTimes(b, a), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,431 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+// Package feed renders the latest verified runs of a game, category or user
+// as Atom or RSS 2.0 documents.
+package feed
+
+import (
+	"encoding/xml"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+)
+
+// Embeds are the embeds used when fetching runs for a feed, so that entries
+// can be rendered without further requests.
+const Embeds = "game,category,level,players"
+
+// DefaultLimit is the number of entries in a feed if no limit is given.
+const DefaultLimit = 20
+
+// Feed is a list of runs, ready to be rendered.
+type Feed struct {
+	Title string
+
+	// link to the website the feed is about, and the feed's unique ID
+	Link string
+
+	// the most recent verification date of all entries
+	Updated time.Time
+
+	Entries []Entry
+}
+
+// Entry is a single run in a feed.
+type Entry struct {
+	// the run's weblink, also used as the entry's unique ID
+	Link string
+
+	// e.g. "Super Mario World: 11 Exit in 10:52.340 by someone"
+	Title string
+
+	// the date the run was verified
+	Updated time.Time
+
+	Game     string
+	Category string
+	Level    string
+	Runners  []string
+	Time     string
+	Values   []srapi.LabeledValue
+	Videos   []string
+	Comment  string
+}
+
+// Fetch builds a feed of the latest verified runs matching the filter (e.g.
+// a game, category or user), newest verification first. The filter's Status
+// is ignored. If limit is not positive, DefaultLimit is used.
+func Fetch(filter *srapi.RunFilter, title string, link string, limit int) (*Feed, *srapi.Error) {
+	runs, err := fetchRuns(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(title, link, runs)
+}
+
+// New builds a feed from runs. Game, category, level and players are taken
+// from the embedded data if available and fetched otherwise; variable values
+// are resolved to their labels (one request per game, unless the variables
+// have been embedded).
+func New(title string, link string, runs []*srapi.Run) (*Feed, *srapi.Error) {
+	feed := &Feed{Title: title, Link: link}
+	resolver := srapi.NewVariableResolver()
+
+	for _, run := range runs {
+		entry, err := newEntry(run, resolver)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Updated.After(feed.Updated) {
+			feed.Updated = entry.Updated
+		}
+
+		feed.Entries = append(feed.Entries, *entry)
+	}
+
+	return feed, nil
+}
+
+// newEntry renders a single run.
+func newEntry(run *srapi.Run, resolver *srapi.VariableResolver) (*Entry, *srapi.Error) {
+	entry := &Entry{Link: run.Weblink, Comment: run.Comment}
+
+	if run.Status.VerifyDate != nil {
+		entry.Updated = *run.Status.VerifyDate
+	} else if run.Submitted != nil {
+		entry.Updated = *run.Submitted
+	}
+
+	game, err := run.Game(srapi.NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	cat, err := run.Category(srapi.NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := run.Level(srapi.NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := run.Players()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := resolver.Run(run)
+	if err != nil {
+		return nil, err
+	}
+
+	if game != nil {
+		entry.Game = game.Names.International
+
+		if run.Times.Primary != nil {
+			entry.Time = game.FormatTime(run.Times.Primary, srapi.ClockStyle)
+		}
+	}
+
+	if cat != nil {
+		entry.Category = cat.Name
+	}
+
+	if level != nil {
+		entry.Level = level.Name
+	}
+
+	for _, player := range players.Players() {
+		entry.Runners = append(entry.Runners, player.Name())
+	}
+
+	for _, video := range run.Videos.Links {
+		entry.Videos = append(entry.Videos, video.URI)
+	}
+
+	entry.Values = values
+
+	// "Game: Category (Level) in 1:02:03 by A and B"
+	entry.Title = entry.Game + ": " + entry.Category
+
+	if len(entry.Level) > 0 {
+		entry.Title += " (" + entry.Level + ")"
+	}
+
+	if len(entry.Time) > 0 {
+		entry.Title += " in " + entry.Time
+	}
+
+	if len(entry.Runners) > 0 {
+		entry.Title += " by " + joinNames(entry.Runners)
+	}
+
+	return entry, nil
+}
+
+// Summary renders the entry's details as HTML.
+func (e *Entry) Summary() string {
+	var lines []string
+
+	if len(e.Runners) > 0 {
+		lines = append(lines, "Runners: "+html.EscapeString(joinNames(e.Runners)))
+	}
+
+	if len(e.Time) > 0 {
+		lines = append(lines, "Time: "+html.EscapeString(e.Time))
+	}
+
+	for _, value := range e.Values {
+		lines = append(lines, html.EscapeString(value.VariableName+": "+value.Label))
+	}
+
+	for _, video := range e.Videos {
+		lines = append(lines, "Video: "+linkHTML(video, video))
+	}
+
+	if len(e.Comment) > 0 {
+		lines = append(lines, "Comment: "+html.EscapeString(e.Comment))
+	}
+
+	if len(e.Link) > 0 {
+		lines = append(lines, linkHTML(e.Link, "View on speedrun.com"))
+	}
+
+	return "<p>" + strings.Join(lines, "<br>\n") + "</p>"
+}
+
+// linkHTML renders a link, or only its text if the target is not a http(s)
+// URL.
+func linkHTML(target string, text string) string {
+	if !srapi.IsWebLink(target) {
+		return html.EscapeString(text)
+	}
+
+	return `<a href="` + html.EscapeString(target) + `">` + html.EscapeString(text) + `</a>`
+}
+
+// atomFeed and the following types model the Atom format (RFC 4287).
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Updated string       `xml:"updated"`
+	Link    atomLink     `xml:"link"`
+	Authors []atomPerson `xml:"author"`
+	Content atomContent  `xml:"content"`
+}
+
+// Atom renders the feed as an Atom document.
+func (f *Feed) Atom() ([]byte, error) {
+	doc := atomFeed{
+		Title:   f.Title,
+		ID:      f.Link,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{f.Link},
+	}
+
+	for idx := range f.Entries {
+		entry := &f.Entries[idx]
+
+		rendered := atomEntry{
+			Title:   entry.Title,
+			ID:      entry.Link,
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{entry.Link},
+			Content: atomContent{"html", entry.Summary()},
+		}
+
+		for _, runner := range entry.Runners {
+			rendered.Authors = append(rendered.Authors, atomPerson{runner})
+		}
+
+		doc.Entries = append(doc.Entries, rendered)
+	}
+
+	return marshal(doc)
+}
+
+// rssDocument and the following types model the RSS 2.0 format.
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+// RSS renders the feed as an RSS 2.0 document.
+func (f *Feed) RSS() ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Title,
+		},
+	}
+
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for idx := range f.Entries {
+		entry := &f.Entries[idx]
+
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Summary(),
+			GUID:        rssGUID{true, entry.Link},
+			PubDate:     entry.Updated.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return marshal(doc)
+}
+
+// Handler serves a feed over HTTP. It renders Atom by default and RSS 2.0 if
+// the query string contains "format=rss". Runs are fetched on every request.
+type Handler struct {
+	Filter srapi.RunFilter
+	Title  string
+	Link   string
+	Limit  int
+
+	// replaced in tests
+	fetch func(filter *srapi.RunFilter, limit int) ([]*srapi.Run, *srapi.Error)
+}
+
+// NewHandler creates a handler for the runs matching the filter.
+func NewHandler(filter *srapi.RunFilter, title string, link string) *Handler {
+	h := &Handler{Title: title, Link: link, Limit: DefaultLimit, fetch: fetchRuns}
+
+	if filter != nil {
+		h.Filter = *filter
+	}
+
+	return h
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.fetch(&h.Filter, h.Limit)
+	if err != nil {
+		http.Error(w, err.Message, http.StatusBadGateway)
+		return
+	}
+
+	feed, err := New(h.Title, h.Link, runs)
+	if err != nil {
+		http.Error(w, err.Message, http.StatusBadGateway)
+		return
+	}
+
+	contentType := "application/atom+xml; charset=utf-8"
+	render := feed.Atom
+
+	if r.URL.Query().Get("format") == "rss" {
+		contentType = "application/rss+xml; charset=utf-8"
+		render = feed.RSS
+	}
+
+	body, renderErr := render()
+	if renderErr != nil {
+		http.Error(w, renderErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// listRuns fetches a page of runs; replaced in tests
+var listRuns = srapi.Runs
+
+// fetchRuns fetches the latest verified runs. Only as many runs as needed are
+// taken, so no further pages are requested.
+func fetchRuns(filter *srapi.RunFilter, limit int) ([]*srapi.Run, *srapi.Error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	verified := srapi.RunFilter{}
+	if filter != nil {
+		verified = *filter
+	}
+
+	verified.Status = "verified"
+
+	runs, err := listRuns(&verified, &srapi.Sorting{OrderBy: "verify-date", Direction: srapi.Descending}, &srapi.Cursor{Offset: 0, Max: limit}, Embeds)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs.Limit(limit).Runs(), nil
+}
+
+// marshal encodes an XML document including the XML header.
+func marshal(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
+
+// joinNames lists names like "A", "A and B" or "A, B and C".
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
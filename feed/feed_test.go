@@ -0,0 +1,147 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sgt-kabukiman/srapi"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testRun = `{
+	"id": "run1",
+	"weblink": "http://www.speedrun.com/run/run1",
+	"videos": {"links": [{"uri": "https://youtu.be/dQw4w9WgXcQ"}]},
+	"comment": "GG <3",
+	"status": {"status": "verified", "verify-date": "2015-08-02T10:00:00Z"},
+	"times": {"primary_t": 652.34},
+	"values": {"diff": "hard"},
+	"game": {"data": {
+		"id": "smw",
+		"names": {"international": "Super Mario World"},
+		"ruleset": {"show-milliseconds": true},
+		"variables": {"data": [
+			{"id": "diff", "name": "Difficulty", "values": {"choices": {"hard": "Hard"}}}
+		]}
+	}},
+	"category": {"data": {"id": "any", "name": "11 Exit"}},
+	"players": {"data": [
+		{"rel": "user", "id": "u1", "names": {"international": "Alice"}},
+		{"rel": "guest", "name": "Bob"}
+	]}
+}`
+
+func testRuns() []*srapi.Run {
+	run := &srapi.Run{}
+	if err := json.Unmarshal([]byte(testRun), run); err != nil {
+		panic(err)
+	}
+
+	return []*srapi.Run{run}
+}
+
+func TestFeeds(t *testing.T) {
+	Convey("Building feeds", t, func() {
+		feed, err := New("Latest SMW runs", "http://www.speedrun.com/smw", testRuns())
+		So(err, ShouldBeNil)
+		So(feed.Updated.Format("2006-01-02"), ShouldEqual, "2015-08-02")
+		So(len(feed.Entries), ShouldEqual, 1)
+
+		entry := feed.Entries[0]
+		So(entry.Title, ShouldEqual, "Super Mario World: 11 Exit in 10:52.340 by Alice and Bob")
+		So(entry.Values[0].Label, ShouldEqual, "Hard")
+
+		summary := entry.Summary()
+		So(summary, ShouldContainSubstring, "Difficulty: Hard")
+		So(summary, ShouldContainSubstring, `<a href="https://youtu.be/dQw4w9WgXcQ">`)
+		So(summary, ShouldContainSubstring, "GG &lt;3")
+
+		entry.Videos = []string{"javascript:alert(1)"}
+		entry.Link = "JavaScript:alert(2)"
+
+		summary = entry.Summary()
+		So(summary, ShouldContainSubstring, "Video: javascript:alert(1)")
+		So(summary, ShouldNotContainSubstring, "href")
+	})
+
+	Convey("Rendering feeds", t, func() {
+		feed, _ := New("Latest SMW runs", "http://www.speedrun.com/smw", testRuns())
+
+		atom, err := feed.Atom()
+		So(err, ShouldBeNil)
+
+		parsedAtom := atomFeed{}
+		So(xml.Unmarshal(atom, &parsedAtom), ShouldBeNil)
+		So(parsedAtom.Updated, ShouldEqual, "2015-08-02T10:00:00Z")
+		So(parsedAtom.Entries[0].ID, ShouldEqual, "http://www.speedrun.com/run/run1")
+		So(parsedAtom.Entries[0].Authors, ShouldResemble, []atomPerson{{"Alice"}, {"Bob"}})
+
+		rss, err := feed.RSS()
+		So(err, ShouldBeNil)
+
+		parsedRSS := rssDocument{}
+		So(xml.Unmarshal(rss, &parsedRSS), ShouldBeNil)
+		So(parsedRSS.Version, ShouldEqual, "2.0")
+		So(parsedRSS.Channel.Items[0].PubDate, ShouldEqual, "Sun, 02 Aug 2015 10:00:00 +0000")
+		So(parsedRSS.Channel.Items[0].Description, ShouldContainSubstring, "Time: 10:52.340")
+	})
+
+	Convey("Serving feeds", t, func() {
+		h := NewHandler(&srapi.RunFilter{Game: "smw"}, "Latest SMW runs", "http://www.speedrun.com/smw")
+		h.fetch = func(filter *srapi.RunFilter, limit int) ([]*srapi.Run, *srapi.Error) {
+			So(filter.Game, ShouldEqual, "smw")
+			So(limit, ShouldEqual, DefaultLimit)
+
+			return testRuns(), nil
+		}
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest("GET", "/feed", nil))
+		So(recorder.Code, ShouldEqual, http.StatusOK)
+		So(recorder.Header().Get("Content-Type"), ShouldStartWith, "application/atom+xml")
+
+		recorder = httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest("GET", "/feed?format=rss", nil))
+		So(recorder.Header().Get("Content-Type"), ShouldStartWith, "application/rss+xml")
+		So(recorder.Body.String(), ShouldContainSubstring, "<rss version=\"2.0\">")
+
+		h.fetch = func(filter *srapi.RunFilter, limit int) ([]*srapi.Run, *srapi.Error) {
+			return nil, &srapi.Error{Status: srapi.ErrorNetwork, Message: "down"}
+		}
+
+		recorder = httptest.NewRecorder()
+		h.ServeHTTP(recorder, httptest.NewRequest("GET", "/feed", nil))
+		So(recorder.Code, ShouldEqual, http.StatusBadGateway)
+	})
+	Convey("Fetching runs only takes the first runs", t, func() {
+		defer func(original func(*srapi.RunFilter, *srapi.Sorting, *srapi.Cursor, string) (*srapi.RunCollection, *srapi.Error)) {
+			listRuns = original
+		}(listRuns)
+
+		listRuns = func(filter *srapi.RunFilter, sorting *srapi.Sorting, cursor *srapi.Cursor, embeds string) (*srapi.RunCollection, *srapi.Error) {
+			So(filter.Status, ShouldEqual, "verified")
+			So(sorting.OrderBy, ShouldEqual, "verify-date")
+			So(cursor.Max, ShouldEqual, 2)
+
+			// the next page must never be requested
+			return &srapi.RunCollection{
+				Data: []srapi.Run{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+				Pagination: srapi.Pagination{
+					Max:   2,
+					Size:  2,
+					Links: []srapi.Link{{Relation: "next", URI: srapi.BaseURL + "/runs?offset=2"}},
+				},
+			}, nil
+		}
+
+		runs, err := fetchRuns(&srapi.RunFilter{Game: "smw"}, 2)
+		So(err, ShouldBeNil)
+		So(len(runs), ShouldEqual, 2)
+		So(runs[1].ID, ShouldEqual, "b")
+	})
+}
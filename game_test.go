@@ -109,6 +109,31 @@ func TestGames(t *testing.T) {
 		})
 	})
 
+	Convey("Accessing games directly", t, func() {
+		games, err := Games(nil, &Sorting{"created", Ascending}, &Cursor{0, 2}, NoEmbeds)
+		So(err, ShouldBeNil)
+
+		reference, err := Games(nil, &Sorting{"created", Ascending}, &Cursor{0, 10}, NoEmbeds)
+		So(err, ShouldBeNil)
+
+		Convey("by index", func() {
+			before := requestCount
+			game := games.Get(7)
+			So(game, ShouldNotBeNil)
+			So(game.ID, ShouldEqual, reference.Data[7].ID)
+			So(requestCount, ShouldEqual, before+1)
+		})
+
+		Convey("by range", func() {
+			slice := games.Slice(1, 8)
+			So(slice, ShouldHaveLength, 7)
+
+			for idx, game := range slice {
+				So(game.ID, ShouldEqual, reference.Data[idx+1].ID)
+			}
+		})
+	})
+
 	Convey("Get the series from a game", t, func() {
 		game, err := GameByAbbreviation("gtavc", NoEmbeds)
 
@@ -29,6 +29,14 @@ const ErrorBadLogic = 903
 // move around, this is bad.
 const ErrorNoSuchLink = 904
 
+// ErrorOffsetLimit represents the case when a query yields more results than
+// can be reached through pagination and the query could not be split into
+// smaller ones.
+const ErrorOffsetLimit = 905
+
+// ErrorStateStore represents a failure of the StateStore used by a Watcher.
+const ErrorStateStore = 906
+
 // BaseURL is the base URL for all API calls.
 const BaseURL = "http://www.speedrun.com/api/v1"
 
@@ -158,6 +166,11 @@ func (ac *apiClient) do(request request, dst interface{}) *Error {
 			return failedRequest(request, nil, err, ErrorBadJSON)
 		}
 
+		// let collections know where they came from, so they can be resumed
+		if src, okay := dst.(sourced); okay {
+			src.setSource(u.String())
+		}
+
 		// everything went fine
 		return nil
 	}
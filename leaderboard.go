@@ -5,6 +5,7 @@ package srapi
 import (
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // Leaderboard represents a leaderboard, i.e. a collection of ranked runs for a
@@ -68,7 +69,7 @@ type RankedRun struct {
 	Run Run
 
 	// the rank, starting at 1
-	Rank int
+	Rank int `json:"place"`
 }
 
 // leaderboardResponse models the actual API response from the server
@@ -229,13 +230,19 @@ type LeaderboardOptions struct {
 	// choice was made.
 	Timing TimingMethod
 
-	// ISO 8601 date; when given, only runs done before this date will be considerd
+	// ISO 8601 date; when given, only runs done on or before this date will be considered
 	Date string
 
 	// map of variable IDs to value IDs
 	Values map[string]string
 }
 
+// SetDate restricts the leaderboard to runs done on or before the given day. Use
+// this instead of setting Date directly to make sure the date is well-formed.
+func (lo *LeaderboardOptions) SetDate(date time.Time) {
+	lo.Date = date.Format(dateLayout)
+}
+
 // applyToURL merged the filter into a URL.
 func (lo *LeaderboardOptions) applyToURL(u *url.URL) {
 	if lo == nil {
@@ -3,12 +3,15 @@
 
 package srapi
 
+import "sort"
+
 // LeaderboardCollection is list of Leaderboard structs. It possible represents
 // a slice of the entire dataset and has links to navigate through the pages.
 type LeaderboardCollection struct {
 	Data       []Leaderboard
 	Pagination Pagination
 	limit      int
+	source     string
 }
 
 // LeaderboardWalkerFunc is a function that can be used in Walk(). If it returns
@@ -24,6 +27,7 @@ func (c *LeaderboardCollection) Limit(limit int) *LeaderboardCollection {
 		Data:       c.Data,
 		Pagination: c.Pagination,
 		limit:      limit,
+		source:     c.source,
 	}
 }
 
@@ -55,8 +59,13 @@ func (c *LeaderboardCollection) Walk(f LeaderboardWalkerFunc) {
 }
 
 // Size returns the number of elements in the collection; returns -1 if the total
-// number cannot be determined without iterating over additional pages (which
-// requires network roundtrips) and fetchAllPages is set to false
+// number cannot be determined without fetching additional pages (which
+// requires network roundtrips) and fetchAllPages is set to false. Instead of
+// walking through all pages, the size is determined by probing single
+// elements, so only a logarithmic number of requests is performed. Like
+// walking, this only counts the elements that can be reached: elements at or
+// beyond MaxOffset count as missing. -1 is also returned if one of the
+// requests fails.
 func (c *LeaderboardCollection) Size(fetchAllPages bool) int {
 	length := len(c.Data)
 	if c.limit > 0 && length > c.limit {
@@ -73,35 +82,104 @@ func (c *LeaderboardCollection) Size(fetchAllPages bool) int {
 		return length
 	}
 
+	// there are no further pages
+	source := pageSource(c.source, &c.Pagination)
+	if len(source) == 0 {
+		return length
+	}
+
 	if !fetchAllPages {
 		return -1
 	}
 
-	count := 0
+	probe := func(idx int) (bool, *Error) {
+		if c.Pagination.Offset+idx >= MaxOffset {
+			return false, nil
+		}
 
-	c.Walk(func(item *Leaderboard) bool {
-		count++
-		return true
-	})
+		page, err := fetchLeaderboards(pageRequest(source, &Cursor{c.Pagination.Offset + idx, 1}))
+		if err != nil {
+			return false, err
+		}
+
+		return len(page.Data) > 0, nil
+	}
+
+	size, err := searchSize(length, c.limit, probe)
+	if err != nil {
+		return -1
+	}
 
-	return count
+	return size
 }
 
 // Get returns the n-th element (the first one has idx 0) and nil if there is
-// no such index.
+// no such index. If the element is not on the current page, only the one page
+// containing it is fetched.
 func (c *LeaderboardCollection) Get(idx int) *Leaderboard {
-	cur := 0
-	it := c.Iterator()
+	if idx < 0 || (c.limit > 0 && idx >= c.limit) {
+		return nil
+	}
 
-	for item := range it.Output() {
-		if cur == idx {
-			return item
+	// easy, the idx is on this page
+	if idx < len(c.Data) {
+		return &c.Data[idx]
+	}
+
+	source := pageSource(c.source, &c.Pagination)
+	if len(source) == 0 {
+		return nil
+	}
+
+	page, err := fetchLeaderboards(pageRequest(source, &Cursor{c.Pagination.Offset + idx, 1}))
+	if err != nil || len(page.Data) == 0 {
+		return nil
+	}
+
+	return &page.Data[0]
+}
+
+// Slice returns the elements with an index between from (inclusive) and to
+// (exclusive). Only the pages containing those elements are fetched, so
+// elements before from are never transferred. The result is shorter than
+// to-from if the collection has not enough elements or fetching a page
+// failed.
+func (c *LeaderboardCollection) Slice(from, to int) []*Leaderboard {
+	var result []*Leaderboard
+
+	if from < 0 {
+		from = 0
+	}
+
+	if c.limit > 0 && to > c.limit {
+		to = c.limit
+	}
+
+	for ; from < to && from < len(c.Data); from++ {
+		result = append(result, &c.Data[from])
+	}
+
+	source := pageSource(c.source, &c.Pagination)
+
+	for from < to && len(source) > 0 {
+		max := to - from
+		if max > maxPageSize {
+			max = maxPageSize
+		}
+
+		page, err := fetchLeaderboards(pageRequest(source, &Cursor{c.Pagination.Offset + from, max}))
+		if err != nil || len(page.Data) == 0 {
+			break
 		}
 
-		cur++
+		for idx := range page.Data {
+			result = append(result, &page.Data[idx])
+		}
+
+		from += len(page.Data)
 	}
 
-	return nil
+	return result
 }
 
 // First returns the first element, if any, otherwise nil.
@@ -113,16 +191,37 @@ func (c *LeaderboardCollection) First() *Leaderboard {
 	return &c.Data[0]
 }
 
+// setSource remembers the URL the collection has been fetched from.
+func (c *LeaderboardCollection) setSource(source string) {
+	c.source = source
+}
+
+// ResumeLeaderboards fetches the page a Position points to and returns it as a
+// new collection, so that iterating it continues right where the iterator
+// that created the position has stopped. Filter, sorting, embeds and the
+// page size are taken from the position.
+func ResumeLeaderboards(pos Position) (*LeaderboardCollection, *Error) {
+	if !pos.exists() {
+		return &LeaderboardCollection{}, &Error{"", "", ErrorBadLogic, "The given position is empty or invalid."}
+	}
+
+	return fetchLeaderboards(pos.request(nil, nil, NoEmbeds))
+}
+
 // Iterator returns an interator for a LeaderboardCollection. There can be many
 // independent iterators starting from the same collection.
 func (c *LeaderboardCollection) Iterator() LeaderboardIterator {
 	it := LeaderboardIterator{
 		output:     make(chan *Leaderboard),
 		killSwitch: make(chan struct{}),
+		done:       make(chan struct{}),
 		origin:     c,
 		limit:      c.limit,
+		state:      &iteratorState{},
 	}
 
+	it.state.advance(c.source, c.Pagination.Offset)
+
 	go it.work()
 
 	return it
@@ -132,8 +231,10 @@ func (c *LeaderboardCollection) Iterator() LeaderboardIterator {
 type LeaderboardIterator struct {
 	output     chan *Leaderboard
 	killSwitch chan struct{}
+	done       chan struct{}
 	origin     *LeaderboardCollection
 	limit      int
+	state      *iteratorState
 }
 
 // Output returns a channel that can be used to read all leaderboards
@@ -148,9 +249,21 @@ func (i *LeaderboardIterator) Output() <-chan *Leaderboard {
 func (i *LeaderboardIterator) Stop() {
 	close(i.killSwitch)
 
-	// drain the remaining element(s)
-	for _ = range i.output {
-	}
+	// wait for the worker to give up; not draining the output makes sure no
+	// item is silently swallowed and the position stays accurate
+	<-i.done
+}
+
+// Position returns the position right after the last leaderboard that has been
+// read from the iterator. It can be persisted and later be given to
+// ResumeLeaderboards() to continue where this iterator left off. While the
+// iterator is running, the position may lag behind by one item (so resuming
+// from it can yield an item twice, but never skips one); after Stop() has been
+// called or all items have been read, it is exact. For collections that have
+// not been fetched from the network (e.g. embedded ones), the zero Position
+// is returned.
+func (i *LeaderboardIterator) Position() Position {
+	return i.state.position()
 }
 
 // work is the goroutine that reads items from the current page and
@@ -160,6 +273,7 @@ func (i *LeaderboardIterator) work() {
 	first := true
 	remaining := i.limit
 
+	defer close(i.done)
 	defer close(i.output)
 
 	for {
@@ -189,6 +303,7 @@ func (i *LeaderboardIterator) work() {
 
 				// use this page from now on
 				page = p
+				i.state.advance(page.source, page.Pagination.Offset)
 			}
 
 			for idx := 0; idx < len(page.Data); idx++ {
@@ -196,8 +311,8 @@ func (i *LeaderboardIterator) work() {
 				case <-i.killSwitch:
 					return
 
-				default:
-					i.output <- &page.Data[idx]
+				case i.output <- &page.Data[idx]:
+					i.state.advance(page.source, page.Pagination.Offset+idx+1)
 					remaining--
 				}
 
@@ -211,3 +326,153 @@ func (i *LeaderboardIterator) work() {
 		}
 	}
 }
+
+// LeaderboardQuery is a lazy sequence of leaderboards, created from a
+// LeaderboardCollection. Queries can be refined by chaining Filter(), Skip(),
+// Take(), SortBy() etc., which does not perform any work until the query is
+// evaluated by calling Walk(), Collect(), GroupBy() or Map(). Evaluating a
+// query walks through the original collection, fetching pages as needed, and
+// every evaluation starts from scratch.
+type LeaderboardQuery struct {
+	walk func(LeaderboardWalkerFunc)
+}
+
+// Query returns a lazy query over all elements of the collection.
+func (c *LeaderboardCollection) Query() *LeaderboardQuery {
+	return &LeaderboardQuery{c.Walk}
+}
+
+// Filter is a shortcut for c.Query().Filter(keep).
+func (c *LeaderboardCollection) Filter(keep func(l *Leaderboard) bool) *LeaderboardQuery {
+	return c.Query().Filter(keep)
+}
+
+// SortBy is a shortcut for c.Query().SortBy(less).
+func (c *LeaderboardCollection) SortBy(less func(a, b *Leaderboard) bool) *LeaderboardQuery {
+	return c.Query().SortBy(less)
+}
+
+// GroupBy is a shortcut for c.Query().GroupBy(key).
+func (c *LeaderboardCollection) GroupBy(key func(l *Leaderboard) string) map[string][]*Leaderboard {
+	return c.Query().GroupBy(key)
+}
+
+// Map is a shortcut for c.Query().Map(f).
+func (c *LeaderboardCollection) Map(f func(l *Leaderboard) interface{}) []interface{} {
+	return c.Query().Map(f)
+}
+
+// Filter returns a query that only contains the elements for which keep
+// returns true.
+func (q *LeaderboardQuery) Filter(keep func(l *Leaderboard) bool) *LeaderboardQuery {
+	return &LeaderboardQuery{func(f LeaderboardWalkerFunc) {
+		q.walk(func(item *Leaderboard) bool {
+			if !keep(item) {
+				return true
+			}
+
+			return f(item)
+		})
+	}}
+}
+
+// Skip returns a query that omits the first n elements.
+func (q *LeaderboardQuery) Skip(n int) *LeaderboardQuery {
+	return &LeaderboardQuery{func(f LeaderboardWalkerFunc) {
+		skipped := 0
+
+		q.walk(func(item *Leaderboard) bool {
+			if skipped < n {
+				skipped++
+				return true
+			}
+
+			return f(item)
+		})
+	}}
+}
+
+// Take returns a query that stops after the first n elements. Evaluating it
+// does not fetch any more pages than needed for those.
+func (q *LeaderboardQuery) Take(n int) *LeaderboardQuery {
+	return &LeaderboardQuery{func(f LeaderboardWalkerFunc) {
+		if n <= 0 {
+			return
+		}
+
+		taken := 0
+
+		q.walk(func(item *Leaderboard) bool {
+			taken++
+
+			return f(item) && taken < n
+		})
+	}}
+}
+
+// SortBy returns a query that yields the elements ordered by the given less
+// function. Elements that are equal keep their original order. As sorting
+// requires all elements, evaluating the query fetches all pages of the
+// underlying collection (unless Take() has been applied before).
+func (q *LeaderboardQuery) SortBy(less func(a, b *Leaderboard) bool) *LeaderboardQuery {
+	return &LeaderboardQuery{func(f LeaderboardWalkerFunc) {
+		items := q.Collect()
+
+		sort.SliceStable(items, func(i, j int) bool {
+			return less(items[i], items[j])
+		})
+
+		for _, item := range items {
+			if !f(item) {
+				return
+			}
+		}
+	}}
+}
+
+// Walk evaluates the query and applies a function to all resulting elements,
+// in order. If the function returns false, iterating will be stopped.
+func (q *LeaderboardQuery) Walk(f LeaderboardWalkerFunc) {
+	q.walk(f)
+}
+
+// Collect evaluates the query and returns all resulting elements.
+func (q *LeaderboardQuery) Collect() []*Leaderboard {
+	var result []*Leaderboard
+
+	q.walk(func(item *Leaderboard) bool {
+		result = append(result, item)
+		return true
+	})
+
+	return result
+}
+
+// GroupBy evaluates the query and groups the resulting elements by the key
+// returned from the given function. Within each group, the order of the
+// elements is retained.
+func (q *LeaderboardQuery) GroupBy(key func(l *Leaderboard) string) map[string][]*Leaderboard {
+	result := make(map[string][]*Leaderboard)
+
+	q.walk(func(item *Leaderboard) bool {
+		k := key(item)
+		result[k] = append(result[k], item)
+
+		return true
+	})
+
+	return result
+}
+
+// Map evaluates the query and returns the result of applying f to each of
+// the resulting elements.
+func (q *LeaderboardQuery) Map(f func(l *Leaderboard) interface{}) []interface{} {
+	var result []interface{}
+
+	q.walk(func(item *Leaderboard) bool {
+		result = append(result, f(item))
+		return true
+	})
+
+	return result
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import "sort"
+
+// LeaderboardDiff describes the changes between two snapshots of the same
+// leaderboard. Runs are identified by their ID. All fields can be encoded as
+// JSON, so a diff can be stored or handed to other services.
+type LeaderboardDiff struct {
+	// runs that are only on the new leaderboard, best first
+	Added []RankedRun `json:"added"`
+
+	// runs that are only on the old leaderboard, best first
+	Removed []RankedRun `json:"removed"`
+
+	// runs that are on both leaderboards, but with a different rank
+	RankChanges []RankChange `json:"rank-changes"`
+
+	// runs that hold the first place on the new leaderboard with a time (by
+	// the leaderboard's timing method) that is strictly faster than the old
+	// first place's; runs that move up because the record was removed or that
+	// tie with it are not new records
+	NewRecords []RankedRun `json:"new-records"`
+
+	// runs that are on both leaderboards and are tied with a different set
+	// of runs than before
+	TieChanges []TieChange `json:"tie-changes"`
+}
+
+// RankChange is the change of a run's rank between two leaderboard snapshots.
+type RankChange struct {
+	RunID   string `json:"run"`
+	OldRank int    `json:"old-rank"`
+	NewRank int    `json:"new-rank"`
+}
+
+// TieChange describes a run whose ties have changed, i.e. the runs sharing
+// its rank are not the same anymore. The lists contain the IDs of the other
+// runs with the same rank and are empty if the run was/is not tied.
+type TieChange struct {
+	RunID     string   `json:"run"`
+	Rank      int      `json:"rank"`
+	OldTiedTo []string `json:"old-tied-to"`
+	NewTiedTo []string `json:"new-tied-to"`
+}
+
+// Empty returns true if there are no differences at all.
+func (d *LeaderboardDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.RankChanges) == 0 &&
+		len(d.NewRecords) == 0 && len(d.TieChanges) == 0
+}
+
+// DiffLeaderboards compares two snapshots of a leaderboard and reports what
+// has changed from the first to the second one. Both leaderboards must be for
+// the same game, category, level and variable values, otherwise an error is
+// returned. No network requests are performed.
+func DiffLeaderboards(from *Leaderboard, to *Leaderboard) (*LeaderboardDiff, *Error) {
+	if from == nil || to == nil {
+		return nil, &Error{"", "", ErrorBadLogic, "Two leaderboards are required for comparing them."}
+	}
+
+	if !from.Key().Equal(to.Key()) {
+		return nil, &Error{"", "", ErrorBadLogic, "The leaderboards are not for the same game, category, level and values."}
+	}
+
+	diff := &LeaderboardDiff{}
+	oldRuns := rankedRunsByID(from.Runs)
+	newRuns := rankedRunsByID(to.Runs)
+	oldTies := tiesByRunID(from.Runs)
+	newTies := tiesByRunID(to.Runs)
+
+	// the time to beat; nil if there was no record yet
+	var record *Duration
+
+	if len(from.Runs) > 0 && from.Runs[0].Rank == 1 {
+		record = rankingTime(&from.Runs[0].Run, to.Timing)
+	}
+
+	for _, ranked := range to.Runs {
+		previous, existed := oldRuns[ranked.Run.ID]
+
+		if !existed {
+			diff.Added = append(diff.Added, ranked)
+		} else {
+			if previous.Rank != ranked.Rank {
+				diff.RankChanges = append(diff.RankChanges, RankChange{ranked.Run.ID, previous.Rank, ranked.Rank})
+			}
+
+			oldTied := oldTies[ranked.Run.ID]
+			newTied := newTies[ranked.Run.ID]
+
+			if !sameStrings(oldTied, newTied) {
+				diff.TieChanges = append(diff.TieChanges, TieChange{ranked.Run.ID, ranked.Rank, oldTied, newTied})
+			}
+		}
+
+		if ranked.Rank == 1 && CompareTimes(rankingTime(&ranked.Run, to.Timing), record) < 0 {
+			diff.NewRecords = append(diff.NewRecords, ranked)
+		}
+	}
+
+	for _, ranked := range from.Runs {
+		if _, exists := newRuns[ranked.Run.ID]; !exists {
+			diff.Removed = append(diff.Removed, ranked)
+		}
+	}
+
+	return diff, nil
+}
+
+// rankedRunsByID maps run IDs to their ranked runs.
+func rankedRunsByID(runs []RankedRun) map[string]RankedRun {
+	result := make(map[string]RankedRun)
+
+	for _, ranked := range runs {
+		result[ranked.Run.ID] = ranked
+	}
+
+	return result
+}
+
+// tiesByRunID maps the ID of each tied run to the sorted IDs of all other runs
+// that share its rank. Runs that are not tied are not part of the result.
+func tiesByRunID(runs []RankedRun) map[string][]string {
+	byRank := make(map[int][]string)
+
+	for _, ranked := range runs {
+		byRank[ranked.Rank] = append(byRank[ranked.Rank], ranked.Run.ID)
+	}
+
+	result := make(map[string][]string)
+
+	for _, ids := range byRank {
+		if len(ids) < 2 {
+			continue
+		}
+
+		for _, id := range ids {
+			var others []string
+
+			for _, other := range ids {
+				if other != id {
+					others = append(others, other)
+				}
+			}
+
+			sort.Strings(others)
+			result[id] = others
+		}
+	}
+
+	return result
+}
+
+// sameStrings checks if two sorted string lists are equal.
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+
+	return true
+}
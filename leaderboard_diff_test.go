@@ -0,0 +1,160 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testLeaderboard creates a leaderboard for a fixed game and category with
+// runs given as alternating IDs and ranks.
+func testLeaderboard(runs ...interface{}) *Leaderboard {
+	lb := &Leaderboard{
+		GameData:     "om1m3625",
+		CategoryData: "w20p0zkn",
+		Values:       map[string]string{"5lyjpkl4": "4qy4j71d"},
+	}
+
+	for idx := 0; idx < len(runs); idx += 2 {
+		lb.Runs = append(lb.Runs, RankedRun{Run: Run{ID: runs[idx].(string)}, Rank: runs[idx+1].(int)})
+	}
+
+	return lb
+}
+
+// withTimes sets the primary times of the leaderboard's runs, in order.
+func withTimes(lb *Leaderboard, seconds ...int) *Leaderboard {
+	for idx := range seconds {
+		lb.Runs[idx].Run.Times.Primary = &Duration{time.Duration(seconds[idx]) * time.Second}
+	}
+
+	return lb
+}
+
+// decodeLeaderboard decodes a leaderboard as returned by the API.
+func decodeLeaderboard(data string) *Leaderboard {
+	lb := &Leaderboard{}
+	if err := json.Unmarshal([]byte(data), lb); err != nil {
+		panic(err)
+	}
+
+	return lb
+}
+
+func TestLeaderboardDiffs(t *testing.T) {
+	Convey("Comparing identical leaderboards", t, func() {
+		diff, err := DiffLeaderboards(testLeaderboard("a", 1, "b", 2), testLeaderboard("a", 1, "b", 2))
+		So(err, ShouldBeNil)
+		So(diff.Empty(), ShouldBeTrue)
+	})
+
+	Convey("Comparing different leaderboards", t, func() {
+		before := withTimes(testLeaderboard("a", 1, "b", 2, "c", 3, "d", 3, "e", 5), 10, 20, 30, 30, 50)
+		after := withTimes(testLeaderboard("x", 1, "a", 2, "c", 3, "e", 4, "y", 4), 5, 10, 30, 50, 50)
+
+		diff, err := DiffLeaderboards(before, after)
+		So(err, ShouldBeNil)
+		So(diff.Empty(), ShouldBeFalse)
+
+		So(diff.Added, ShouldHaveLength, 2)
+		So(diff.Added[0].Run.ID, ShouldEqual, "x")
+		So(diff.Added[1].Run.ID, ShouldEqual, "y")
+
+		So(diff.Removed, ShouldHaveLength, 2)
+		So(diff.Removed[0].Run.ID, ShouldEqual, "b")
+		So(diff.Removed[1].Run.ID, ShouldEqual, "d")
+
+		So(diff.RankChanges, ShouldResemble, []RankChange{{"a", 1, 2}, {"e", 5, 4}})
+
+		So(diff.NewRecords, ShouldHaveLength, 1)
+		So(diff.NewRecords[0].Run.ID, ShouldEqual, "x")
+
+		So(diff.TieChanges, ShouldResemble, []TieChange{
+			{"c", 3, []string{"d"}, nil},
+			{"e", 4, nil, []string{"y"}},
+		})
+
+		encoded, jsonErr := json.Marshal(diff)
+		So(jsonErr, ShouldBeNil)
+		So(string(encoded), ShouldContainSubstring, `"rank-changes":[{"run":"a","old-rank":1,"new-rank":2}`)
+	})
+
+	Convey("Comparing leaderboards decoded from the API", t, func() {
+		before := decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "values": {"5lyjpkl4": "4qy4j71d"}, "runs": [
+			{"place": 1, "run": {"id": "a", "times": {"primary_t": 100}}},
+			{"place": 2, "run": {"id": "b", "times": {"primary_t": 110}}}
+		]}`)
+
+		after := decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "values": {"5lyjpkl4": "4qy4j71d"}, "runs": [
+			{"place": 1, "run": {"id": "x", "times": {"primary_t": 90}}},
+			{"place": 2, "run": {"id": "a", "times": {"primary_t": 100}}},
+			{"place": 3, "run": {"id": "b", "times": {"primary_t": 110}}}
+		]}`)
+
+		So(after.Runs[2].Rank, ShouldEqual, 3)
+
+		diff, err := DiffLeaderboards(before, after)
+		So(err, ShouldBeNil)
+		So(diff.RankChanges, ShouldResemble, []RankChange{{"a", 1, 2}, {"b", 2, 3}})
+		So(diff.TieChanges, ShouldBeEmpty)
+		So(diff.NewRecords, ShouldHaveLength, 1)
+		So(diff.NewRecords[0].Run.ID, ShouldEqual, "x")
+	})
+
+	Convey("Records are compared by the leaderboard's timing method", t, func() {
+		before := decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "values": {"5lyjpkl4": "4qy4j71d"}, "timing": "realtime", "runs": [
+			{"place": 1, "run": {"id": "a", "times": {"primary_t": 90, "realtime_t": 100, "ingame_t": 90}}}
+		]}`)
+
+		after := decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "values": {"5lyjpkl4": "4qy4j71d"}, "timing": "realtime", "runs": [
+			{"place": 1, "run": {"id": "x", "times": {"primary_t": 95, "realtime_t": 95, "ingame_t": 95}}},
+			{"place": 2, "run": {"id": "a", "times": {"primary_t": 90, "realtime_t": 100, "ingame_t": 90}}}
+		]}`)
+
+		diff, err := DiffLeaderboards(before, after)
+		So(err, ShouldBeNil)
+		So(diff.NewRecords, ShouldHaveLength, 1)
+		So(diff.NewRecords[0].Run.ID, ShouldEqual, "x")
+	})
+
+	Convey("A removed record does not make the runner-up a new record", t, func() {
+		diff, err := DiffLeaderboards(withTimes(testLeaderboard("a", 1, "b", 2), 10, 20), withTimes(testLeaderboard("b", 1), 20))
+		So(err, ShouldBeNil)
+		So(diff.Removed, ShouldHaveLength, 1)
+		So(diff.NewRecords, ShouldBeEmpty)
+	})
+
+	Convey("Tying the record is not a new record", t, func() {
+		diff, err := DiffLeaderboards(withTimes(testLeaderboard("a", 1), 10), withTimes(testLeaderboard("a", 1, "b", 1), 10, 10))
+		So(err, ShouldBeNil)
+		So(diff.Added, ShouldHaveLength, 1)
+		So(diff.NewRecords, ShouldBeEmpty)
+	})
+
+	Convey("The first run on an empty leaderboard is a new record", t, func() {
+		diff, err := DiffLeaderboards(testLeaderboard(), withTimes(testLeaderboard("a", 1), 10))
+		So(err, ShouldBeNil)
+		So(diff.NewRecords, ShouldHaveLength, 1)
+	})
+
+	Convey("Only leaderboards with the same key can be compared", t, func() {
+		other := testLeaderboard("a", 1)
+		other.Values["5lyjpkl4"] = "something else"
+
+		_, err := DiffLeaderboards(testLeaderboard("a", 1), other)
+		So(err, ShouldNotBeNil)
+
+		embedded := testLeaderboard("a", 1)
+		embedded.GameData = map[string]interface{}{"data": map[string]interface{}{"id": "om1m3625"}}
+
+		_, err = DiffLeaderboards(testLeaderboard("a", 1), embedded)
+		So(err, ShouldBeNil)
+
+		_, err = DiffLeaderboards(nil, embedded)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"time"
+)
+
+// LeaderboardSnapshot is a leaderboard as it looked like on a given day,
+// together with a few metrics derived from it.
+type LeaderboardSnapshot struct {
+	// the day of the snapshot
+	Date time.Time
+
+	// the leaderboard, containing only runs done on or before Date
+	Leaderboard *Leaderboard
+
+	// the number of distinct users and guests on the leaderboard
+	Runners int
+
+	// the best time on the leaderboard, nil if it's empty
+	TopTime *Duration
+
+	// the median time of all runs on the leaderboard, nil if it's empty
+	MedianTime *Duration
+}
+
+// LeaderboardHistory fetches a leaderboard once for every given date and returns
+// the snapshots in chronological order, e.g. for drawing charts. options work
+// like for FullGameLeaderboard and LevelLeaderboard, except that their Date is
+// replaced by each of the dates in turn. level must be nil for full-game
+// categories. Dates must not be zero or lie in the future. If no game is
+// given, it is fetched automatically.
+func LeaderboardHistory(game *Game, cat *Category, level *Level, options *LeaderboardOptions, dates []time.Time, embeds string) ([]LeaderboardSnapshot, *Error) {
+	if cat == nil {
+		return nil, &Error{"", "", ErrorBadLogic, "No category given."}
+	}
+
+	now := time.Now()
+	sorted := make([]time.Time, len(dates))
+	copy(sorted, dates)
+
+	for _, date := range sorted {
+		if date.IsZero() || date.After(now) {
+			return nil, &Error{"", "", ErrorBadLogic, "Snapshot dates must neither be empty nor in the future."}
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	if game == nil {
+		var err *Error
+
+		game, err = cat.Game("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result []LeaderboardSnapshot
+
+	for _, date := range sorted {
+		opts := LeaderboardOptions{}
+		if options != nil {
+			opts = *options
+		}
+
+		opts.SetDate(date)
+
+		var lb *Leaderboard
+		var err *Error
+
+		if level == nil {
+			lb, err = FullGameLeaderboard(game, cat, &opts, embeds)
+		} else {
+			lb, err = LevelLeaderboard(game, cat, level, &opts, embeds)
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, newLeaderboardSnapshot(date, lb))
+	}
+
+	return result, nil
+}
+
+// MonthlyDates returns the first day of every month between from and to
+// (both inclusive), to be used with LeaderboardHistory.
+func MonthlyDates(from time.Time, to time.Time) []time.Time {
+	var result []time.Time
+
+	current := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if current.Before(from) {
+		current = current.AddDate(0, 1, 0)
+	}
+
+	for !current.After(to) {
+		result = append(result, current)
+		current = current.AddDate(0, 1, 0)
+	}
+
+	return result
+}
+
+// newLeaderboardSnapshot computes the metrics for a leaderboard. Times are
+// taken according to the leaderboard's timing method, falling back to the
+// primary time of each run.
+func newLeaderboardSnapshot(date time.Time, lb *Leaderboard) LeaderboardSnapshot {
+	snapshot := LeaderboardSnapshot{
+		Date:        date,
+		Leaderboard: lb,
+	}
+
+	runners := make(map[string]bool)
+	var times []time.Duration
+
+	for idx := range lb.Runs {
+		run := &lb.Runs[idx].Run
+
+		links, _ := run.PlayerLinks()
+		for _, link := range links {
+			runners[link.Relation+":"+link.ID+link.Name] = true
+		}
+
+		runTime := run.timeFor(lb.Timing)
+		if runTime == nil {
+			runTime = run.Times.Primary
+		}
+
+		if runTime != nil {
+			times = append(times, runTime.Duration)
+		}
+	}
+
+	snapshot.Runners = len(runners)
+
+	if len(times) > 0 {
+		sort.Slice(times, func(i, j int) bool {
+			return times[i] < times[j]
+		})
+
+		median := times[len(times)/2]
+		if len(times)%2 == 0 {
+			median = (times[len(times)/2-1] + median) / 2
+		}
+
+		snapshot.TopTime = &Duration{times[0]}
+		snapshot.MedianTime = &Duration{median}
+	}
+
+	return snapshot
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLeaderboardHistory(t *testing.T) {
+	Convey("Generating monthly dates", t, func() {
+		from := time.Date(2015, 1, 15, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2015, 4, 1, 0, 0, 0, 0, time.UTC)
+
+		dates := MonthlyDates(from, to)
+		So(dates, ShouldHaveLength, 3)
+		So(dates[0].Format(dateLayout), ShouldEqual, "2015-02-01")
+		So(dates[2].Format(dateLayout), ShouldEqual, "2015-04-01")
+
+		So(MonthlyDates(to, from), ShouldBeEmpty)
+	})
+
+	Convey("Dates are formatted when set as an option", t, func() {
+		options := LeaderboardOptions{}
+		options.SetDate(time.Date(2015, 3, 7, 18, 30, 0, 0, time.UTC))
+		So(options.Date, ShouldEqual, "2015-03-07")
+	})
+
+	Convey("Invalid dates are rejected", t, func() {
+		future := time.Now().AddDate(1, 0, 0)
+
+		_, err := LeaderboardHistory(nil, &Category{}, nil, nil, []time.Time{future}, NoEmbeds)
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, ErrorBadLogic)
+
+		_, err = LeaderboardHistory(nil, &Category{}, nil, nil, []time.Time{{}}, NoEmbeds)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Computing snapshot metrics", t, func() {
+		lb := &Leaderboard{Timing: TimingRealtime}
+
+		for idx, seconds := range []int{100, 110, 130, 200} {
+			run := Run{ID: string(rune('a' + idx))}
+			run.Times.Realtime = &Duration{time.Duration(seconds) * time.Second}
+			run.PlayersData = []interface{}{
+				map[string]interface{}{"rel": "user", "id": string(rune('a' + idx%3))},
+			}
+
+			lb.Runs = append(lb.Runs, RankedRun{run, idx + 1})
+		}
+
+		snapshot := newLeaderboardSnapshot(time.Now(), lb)
+		So(snapshot.Runners, ShouldEqual, 3)
+		So(snapshot.TopTime.Duration, ShouldEqual, 100*time.Second)
+		So(snapshot.MedianTime.Duration, ShouldEqual, 120*time.Second)
+
+		empty := newLeaderboardSnapshot(time.Now(), &Leaderboard{})
+		So(empty.Runners, ShouldEqual, 0)
+		So(empty.TopTime, ShouldBeNil)
+	})
+}
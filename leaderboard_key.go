@@ -0,0 +1,286 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// LeaderboardKey identifies a single leaderboard of a game: a category, a
+// level (only for individual-level categories) and one value for each of the
+// sub-category variables that apply to them.
+type LeaderboardKey struct {
+	// game ID
+	Game string
+
+	// category ID
+	Category string
+
+	// level ID, empty for full-game leaderboards
+	Level string
+
+	// the sub-category values (mapping of variable ID to value ID)
+	Values map[string]string
+}
+
+// String returns a canonical representation of the key, e.g. for using it as
+// a map key, in the form "game/category[/level][?var-ID=value-ID...]".
+func (k LeaderboardKey) String() string {
+	result := k.Game + "/" + k.Category
+
+	if len(k.Level) > 0 {
+		result += "/" + k.Level
+	}
+
+	if len(k.Values) > 0 {
+		values := url.Values{}
+
+		for varID, valueID := range k.Values {
+			values.Set("var-"+varID, valueID)
+		}
+
+		// Encode() sorts by key
+		result += "?" + values.Encode()
+	}
+
+	return result
+}
+
+// ParseLeaderboardKey is the inverse of LeaderboardKey.String(). It parses
+// keys in the form "game/category[/level][?var-ID=value-ID...]".
+func ParseLeaderboardKey(s string) (LeaderboardKey, *Error) {
+	key := LeaderboardKey{}
+	path, query := s, ""
+
+	if idx := strings.IndexByte(s, '?'); idx >= 0 {
+		path, query = s[:idx], s[idx+1:]
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return key, &Error{"", "", ErrorBadLogic, "A leaderboard key must consist of a game, a category and optionally a level."}
+	}
+
+	for _, part := range parts {
+		if len(part) == 0 {
+			return key, &Error{"", "", ErrorBadLogic, "A leaderboard key must not contain empty IDs."}
+		}
+	}
+
+	key.Game = parts[0]
+	key.Category = parts[1]
+
+	if len(parts) == 3 {
+		key.Level = parts[2]
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return key, &Error{"", "", ErrorBadLogic, "Invalid variable values: " + err.Error()}
+	}
+
+	for name, value := range values {
+		if !strings.HasPrefix(name, "var-") || len(name) == 4 || len(value) != 1 || len(value[0]) == 0 {
+			return key, &Error{"", "", ErrorBadLogic, "Variable values must be given once each as var-ID=value-ID."}
+		}
+
+		if key.Values == nil {
+			key.Values = make(map[string]string)
+		}
+
+		key.Values[name[4:]] = value[0]
+	}
+
+	return key, nil
+}
+
+// Equal checks if two keys identify the same leaderboard.
+func (k LeaderboardKey) Equal(other LeaderboardKey) bool {
+	return k.String() == other.String()
+}
+
+// Fetch retrieves the leaderboard identified by the key. The key's values are
+// added to the options' values (overwriting them where necessary).
+func (k LeaderboardKey) Fetch(options *LeaderboardOptions, embeds string) (*Leaderboard, *Error) {
+	opts := LeaderboardOptions{}
+	if options != nil {
+		opts = *options
+	}
+
+	opts.Values = make(map[string]string)
+
+	if options != nil {
+		for varID, valueID := range options.Values {
+			opts.Values[varID] = valueID
+		}
+	}
+
+	for varID, valueID := range k.Values {
+		opts.Values[varID] = valueID
+	}
+
+	path := "/leaderboards/" + k.Game + "/category/" + k.Category
+	if len(k.Level) > 0 {
+		path = "/leaderboards/" + k.Game + "/level/" + k.Level + "/" + k.Category
+	}
+
+	return fetchLeaderboard(request{"GET", path, &opts, nil, nil, embeds})
+}
+
+// Key returns the key identifying the leaderboard.
+func (lb *Leaderboard) Key() LeaderboardKey {
+	return LeaderboardKey{
+		Game:     recastToID(lb.GameData),
+		Category: recastToID(lb.CategoryData),
+		Level:    recastToID(lb.LevelData),
+		Values:   lb.Values,
+	}
+}
+
+// runLeaderboardKey returns the key of the leaderboard a run belongs to, i.e.
+// including the run's values for all sub-category variables among the given
+// ones (usually all variables of the run's game).
+func runLeaderboardKey(run *Run, variables []*Variable) LeaderboardKey {
+	key := LeaderboardKey{
+		Game:     recastToID(run.GameData),
+		Category: recastToID(run.CategoryData),
+		Level:    recastToID(run.LevelData),
+	}
+
+	for _, variable := range variables {
+		valueID, exists := run.Values[variable.ID]
+		if !variable.IsSubcategory || !exists {
+			continue
+		}
+
+		if key.Values == nil {
+			key.Values = make(map[string]string)
+		}
+
+		key.Values[variable.ID] = valueID
+	}
+
+	return key
+}
+
+// LeaderboardKeys enumerates all leaderboards of the game, i.e. every
+// full-game category, every combination of individual-level category and
+// level, each multiplied by all values of the sub-category variables that
+// apply to them. Categories, levels and variables are taken from the embedded
+// data if available and fetched otherwise (so embedding "categories,levels,
+// variables" saves three requests). The result is ordered by category, level
+// and values.
+func (g *Game) LeaderboardKeys() ([]LeaderboardKey, *Error) {
+	categories, err := g.Categories(nil, nil, NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := g.Levels(nil, NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := g.Variables(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []LeaderboardKey
+
+	for _, cat := range categories.Categories() {
+		if cat.Type == "per-level" {
+			for _, level := range levels.Levels() {
+				result = append(result, leaderboardKeys(g.ID, cat, level, variables)...)
+			}
+		} else {
+			result = append(result, leaderboardKeys(g.ID, cat, nil, variables)...)
+		}
+	}
+
+	return result, nil
+}
+
+// AllLeaderboards fetches every leaderboard of the game, as enumerated by
+// LeaderboardKeys(). This causes one request per leaderboard, so use it with
+// care for games with many levels or sub-categories. options are applied to
+// every leaderboard. If one of the requests fails, the leaderboards fetched
+// so far are returned together with the error.
+func (g *Game) AllLeaderboards(options *LeaderboardOptions, embeds string) ([]*Leaderboard, *Error) {
+	keys, err := g.LeaderboardKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Leaderboard
+
+	for _, key := range keys {
+		lb, err := key.Fetch(options, embeds)
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, lb)
+	}
+
+	return result, nil
+}
+
+// leaderboardKeys returns the keys for a category and level (nil for
+// full-game categories), one per combination of sub-category values.
+func leaderboardKeys(gameID string, cat *Category, level *Level, variables *VariableCollection) []LeaderboardKey {
+	var subcategories []*Variable
+
+	for _, variable := range variables.Variables() {
+		if variable.IsSubcategory && len(variable.Values.Choices) > 0 && variable.appliesTo(cat, level) {
+			subcategories = append(subcategories, variable)
+		}
+	}
+
+	sort.Slice(subcategories, func(i, j int) bool {
+		return subcategories[i].ID < subcategories[j].ID
+	})
+
+	levelID := ""
+	if level != nil {
+		levelID = level.ID
+	}
+
+	// build the cartesian product of all values
+	combinations := []map[string]string{{}}
+
+	for _, variable := range subcategories {
+		var choices []string
+		for valueID := range variable.Values.Choices {
+			choices = append(choices, valueID)
+		}
+
+		sort.Strings(choices)
+
+		var next []map[string]string
+
+		for _, combination := range combinations {
+			for _, valueID := range choices {
+				values := make(map[string]string)
+				for varID, other := range combination {
+					values[varID] = other
+				}
+
+				values[variable.ID] = valueID
+				next = append(next, values)
+			}
+		}
+
+		combinations = next
+	}
+
+	var result []LeaderboardKey
+
+	for _, values := range combinations {
+		result = append(result, LeaderboardKey{gameID, cat.ID, levelID, values})
+	}
+
+	return result
+}
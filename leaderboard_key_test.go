@@ -0,0 +1,115 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// embedded wraps a list of resources like the API does for embeds.
+func embedded(items ...map[string]interface{}) map[string]interface{} {
+	list := []interface{}{}
+	for _, item := range items {
+		list = append(list, item)
+	}
+
+	return map[string]interface{}{"data": list}
+}
+
+// testVariable creates the JSON representation of a variable.
+func testVariable(id string, category string, scope string, level string, subcategory bool, values ...string) map[string]interface{} {
+	choices := map[string]interface{}{}
+	for _, value := range values {
+		choices[value] = "Label " + value
+	}
+
+	links := []interface{}{}
+	if len(category) > 0 {
+		links = append(links, map[string]interface{}{"rel": "category", "uri": BaseURL + "/categories/" + category})
+	}
+
+	return map[string]interface{}{
+		"id":             id,
+		"scope":          map[string]interface{}{"type": scope, "level": level},
+		"is-subcategory": subcategory,
+		"values":         map[string]interface{}{"choices": choices},
+		"links":          links,
+	}
+}
+
+func TestLeaderboardKeys(t *testing.T) {
+	game := &Game{ID: "game"}
+
+	game.CategoriesData = embedded(
+		map[string]interface{}{"id": "any", "type": "per-game"},
+		map[string]interface{}{"id": "hundo", "type": "per-game"},
+		map[string]interface{}{"id": "il", "type": "per-level"},
+	)
+
+	game.LevelsData = embedded(
+		map[string]interface{}{"id": "l1"},
+		map[string]interface{}{"id": "l2"},
+	)
+
+	game.VariablesData = embedded(
+		testVariable("diff", "any", "global", "", true, "easy", "hard"),
+		testVariable("char", "", "full-game", "", true, "mario", "luigi"),
+		testVariable("ver", "", "global", "", false, "jp", "us"),
+		testVariable("route", "il", "single-level", "l2", true, "short", "long"),
+	)
+
+	Convey("Enumerating all leaderboards of a game", t, func() {
+		keys, err := game.LeaderboardKeys()
+		So(err, ShouldBeNil)
+
+		var names []string
+		for _, key := range keys {
+			names = append(names, key.String())
+		}
+
+		So(names, ShouldResemble, []string{
+			"game/any?var-char=luigi&var-diff=easy",
+			"game/any?var-char=luigi&var-diff=hard",
+			"game/any?var-char=mario&var-diff=easy",
+			"game/any?var-char=mario&var-diff=hard",
+			"game/hundo?var-char=luigi",
+			"game/hundo?var-char=mario",
+			"game/il/l1",
+			"game/il/l2?var-route=long",
+			"game/il/l2?var-route=short",
+		})
+	})
+
+	Convey("Leaderboards know their key", t, func() {
+		lb := &Leaderboard{
+			GameData:     "game",
+			CategoryData: map[string]interface{}{"data": map[string]interface{}{"id": "il"}},
+			LevelData:    "l1",
+		}
+
+		So(lb.Key().String(), ShouldEqual, "game/il/l1")
+		So(lb.Key().Equal(LeaderboardKey{"game", "il", "l1", map[string]string{}}), ShouldBeTrue)
+		So(lb.Key().Equal(LeaderboardKey{"game", "il", "l2", nil}), ShouldBeFalse)
+	})
+
+	Convey("Keys can be parsed", t, func() {
+		keys := []LeaderboardKey{
+			{"game", "any", "", nil},
+			{"game", "il", "l1", nil},
+			{"game", "any", "", map[string]string{"diff": "hard", "char": "luigi"}},
+		}
+
+		for _, key := range keys {
+			parsed, err := ParseLeaderboardKey(key.String())
+			So(err, ShouldBeNil)
+			So(parsed, ShouldResemble, key)
+		}
+
+		for _, invalid := range []string{"", "game", "game//l1", "a/b/c/d", "game/any?diff=hard", "game/any?var-=x", "game/any?var-diff=a&var-diff=b", "game/any?var-diff=%zz"} {
+			_, err := ParseLeaderboardKey(invalid)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
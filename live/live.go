@@ -0,0 +1,293 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+// Package live serves leaderboards as Server-Sent Events streams, e.g. for
+// stream overlays. Clients connect to "<game>/<category>[/<level>]" (IDs,
+// optionally followed by "?var-<variable ID>=<value ID>..." for sub-categories)
+// and receive the following events:
+//
+//   - "leaderboard": the complete leaderboard as JSON, sent right after
+//     connecting and after every change
+//   - "diff": the changes since the previous poll as JSON (see
+//     srapi.LeaderboardDiff), sent before the updated leaderboard
+//   - "error": a message if the leaderboard could not be fetched
+//
+// No matter how many clients watch a leaderboard, it is only polled once per
+// interval. Polling stops when the last client disconnects.
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+)
+
+// clientBuffer is the number of messages a client may lag behind before it
+// is disconnected.
+const clientBuffer = 16
+
+// Server is an http.Handler serving leaderboard streams. Mount it with
+// http.StripPrefix if it does not live at the root.
+type Server struct {
+	// applied to all leaderboards; their values are merged with the key's
+	Options *srapi.LeaderboardOptions
+
+	// embeds for the leaderboards, e.g. "players" for showing names
+	Embeds string
+
+	interval time.Duration
+	mutex    sync.Mutex
+	feeds    map[string]*feed
+
+	// replaced in tests
+	fetch func(key srapi.LeaderboardKey, options *srapi.LeaderboardOptions, embeds string) (*srapi.Leaderboard, *srapi.Error)
+	after func(time.Duration) <-chan time.Time
+}
+
+// feed is a polled leaderboard and its clients.
+type feed struct {
+	key     srapi.LeaderboardKey
+	clients map[chan message]bool
+	latest  *srapi.Leaderboard
+	stop    chan struct{}
+}
+
+// message is a single server-sent event.
+type message struct {
+	event string
+	data  []byte
+}
+
+// NewServer creates a server that polls each watched leaderboard every
+// interval.
+func NewServer(interval time.Duration) *Server {
+	return &Server{
+		interval: interval,
+		feeds:    make(map[string]*feed),
+
+		fetch: func(key srapi.LeaderboardKey, options *srapi.LeaderboardOptions, embeds string) (*srapi.Leaderboard, *srapi.Error) {
+			return key.Fetch(options, embeds)
+		},
+
+		after: time.After,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Only GET requests are supported.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, err := srapi.ParseLeaderboardKey(strings.Trim(r.URL.Path, "/") + "?" + r.URL.RawQuery)
+	if err != nil {
+		http.Error(w, err.Message, http.StatusNotFound)
+		return
+	}
+
+	flusher, okay := w.(http.Flusher)
+	if !okay {
+		http.Error(w, "Streaming is not supported.", http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+
+	messages, latest := s.subscribe(key)
+	defer s.unsubscribe(key, messages)
+
+	w.WriteHeader(http.StatusOK)
+
+	if latest != nil {
+		writeMessage(w, *latest)
+	}
+
+	flusher.Flush()
+
+	for {
+		select {
+		case msg, open := <-messages:
+			if !open {
+				return
+			}
+
+			writeMessage(w, msg)
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// subscribe registers a client, starting to poll the leaderboard if
+// necessary. It returns the latest leaderboard message, if there is one.
+func (s *Server) subscribe(key srapi.LeaderboardKey) (chan message, *message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, exists := s.feeds[key.String()]
+	if !exists {
+		f = &feed{
+			key:     key,
+			clients: make(map[chan message]bool),
+			stop:    make(chan struct{}),
+		}
+
+		s.feeds[key.String()] = f
+		go s.poll(f)
+	}
+
+	messages := make(chan message, clientBuffer)
+	f.clients[messages] = true
+
+	if f.latest == nil {
+		return messages, nil
+	}
+
+	latest, err := leaderboardMessage(f.latest)
+	if err != nil {
+		return messages, nil
+	}
+
+	return messages, &latest
+}
+
+// unsubscribe removes a client and stops polling if it was the last one.
+func (s *Server) unsubscribe(key srapi.LeaderboardKey, messages chan message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if f, exists := s.feeds[key.String()]; exists {
+		s.remove(f, messages)
+	}
+}
+
+// remove drops a client from a feed and stops polling if it was the last one.
+// It must be called with the mutex held.
+func (s *Server) remove(f *feed, messages chan message) {
+	if !f.clients[messages] {
+		return
+	}
+
+	delete(f.clients, messages)
+	close(messages)
+
+	if len(f.clients) == 0 {
+		close(f.stop)
+
+		if s.feeds[f.key.String()] == f {
+			delete(s.feeds, f.key.String())
+		}
+	}
+}
+
+// poll is the goroutine that fetches a leaderboard until nobody is watching
+// anymore.
+func (s *Server) poll(f *feed) {
+	for {
+		lb, err := s.fetch(f.key, s.Options, s.Embeds)
+
+		s.mutex.Lock()
+		s.broadcast(f, s.update(f, lb, err))
+		s.mutex.Unlock()
+
+		select {
+		case <-f.stop:
+			return
+
+		case <-s.after(s.interval):
+		}
+	}
+}
+
+// update stores the new leaderboard and returns the messages for the
+// clients. It must be called with the mutex held.
+func (s *Server) update(f *feed, lb *srapi.Leaderboard, failure *srapi.Error) []message {
+	if failure != nil {
+		return []message{{"error", []byte(jsonString(failure.Message))}}
+	}
+
+	previous := f.latest
+	f.latest = lb
+
+	current, err := leaderboardMessage(lb)
+	if err != nil {
+		return []message{{"error", []byte(jsonString(err.Error()))}}
+	}
+
+	if previous == nil {
+		return []message{current}
+	}
+
+	diff, failure := srapi.DiffLeaderboards(previous, lb)
+	if failure != nil || diff.Empty() {
+		return nil
+	}
+
+	encoded, err := json.Marshal(diff)
+	if err != nil {
+		return []message{{"error", []byte(jsonString(err.Error()))}}
+	}
+
+	return []message{{"diff", encoded}, current}
+}
+
+// broadcast sends the messages to all clients of the feed. Clients that lag
+// behind are disconnected. It must be called with the mutex held.
+func (s *Server) broadcast(f *feed, messages []message) {
+	for client := range f.clients {
+		for _, msg := range messages {
+			if !send(client, msg) {
+				s.remove(f, client)
+				break
+			}
+		}
+	}
+}
+
+// send queues a message for a client without blocking. It returns false if
+// the client's buffer is full.
+func send(client chan message, msg message) bool {
+	select {
+	case client <- msg:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// leaderboardMessage encodes a leaderboard.
+func leaderboardMessage(lb *srapi.Leaderboard) (message, error) {
+	encoded, err := json.Marshal(lb)
+	if err != nil {
+		return message{}, err
+	}
+
+	return message{"leaderboard", encoded}, nil
+}
+
+// writeMessage writes a message in the text/event-stream format.
+func writeMessage(w http.ResponseWriter, msg message) {
+	w.Write([]byte("event: " + msg.event + "\n"))
+
+	for _, line := range strings.Split(string(msg.data), "\n") {
+		w.Write([]byte("data: " + line + "\n"))
+	}
+
+	w.Write([]byte("\n"))
+}
+
+// jsonString encodes a string as JSON.
+func jsonString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
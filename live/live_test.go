@@ -0,0 +1,164 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stream is a connected SSE client.
+type stream struct {
+	response *http.Response
+	reader   *bufio.Reader
+}
+
+func connect(url string) (*stream, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stream{response, bufio.NewReader(response.Body)}, nil
+}
+
+// next reads the next event and its (single-line) data.
+func (s *stream) next() (string, string) {
+	var event, data string
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", ""
+		}
+
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "":
+			return event, data
+
+		case strings.HasPrefix(line, "event: "):
+			event = line[7:]
+
+		case strings.HasPrefix(line, "data: "):
+			data += line[6:]
+		}
+	}
+}
+
+func (s *stream) close() {
+	s.response.Body.Close()
+}
+
+func TestServer(t *testing.T) {
+	var mutex sync.Mutex
+	fetches := 0
+	ranking := [][]string{{"a"}, {"b", "a"}}
+	times := map[string]time.Duration{"a": 100 * time.Second, "b": 90 * time.Second}
+
+	ticks := make(chan time.Time)
+	waiting := make(chan struct{}, 1)
+
+	server := NewServer(time.Minute)
+	server.fetch = func(key srapi.LeaderboardKey, options *srapi.LeaderboardOptions, embeds string) (*srapi.Leaderboard, *srapi.Error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		lb := &srapi.Leaderboard{GameData: key.Game, CategoryData: key.Category, Values: key.Values}
+
+		for idx, id := range ranking[fetches] {
+			run := srapi.Run{ID: id}
+			run.Times.Primary = &srapi.Duration{Duration: times[id]}
+
+			lb.Runs = append(lb.Runs, srapi.RankedRun{Run: run, Rank: idx + 1})
+		}
+
+		fetches++
+
+		return lb, nil
+	}
+
+	server.after = func(time.Duration) <-chan time.Time {
+		waiting <- struct{}{}
+		return ticks
+	}
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	url := httpServer.URL + "/game/cat?var-diff=hard"
+
+	Convey("Streaming a leaderboard", t, func() {
+		first, err := connect(url)
+		So(err, ShouldBeNil)
+		So(first.response.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+
+		event, data := first.next()
+		So(event, ShouldEqual, "leaderboard")
+
+		lb := srapi.Leaderboard{}
+		So(json.Unmarshal([]byte(data), &lb), ShouldBeNil)
+		So(lb.Runs[0].Run.ID, ShouldEqual, "a")
+		So(lb.Values, ShouldResemble, map[string]string{"diff": "hard"})
+
+		<-waiting
+
+		// the second client gets the current leaderboard without polling
+		second, err := connect(url)
+		So(err, ShouldBeNil)
+
+		event, _ = second.next()
+		So(event, ShouldEqual, "leaderboard")
+		So(fetches, ShouldEqual, 1)
+
+		ticks <- time.Now()
+
+		for _, client := range []*stream{first, second} {
+			event, data = client.next()
+			So(event, ShouldEqual, "diff")
+
+			diff := srapi.LeaderboardDiff{}
+			So(json.Unmarshal([]byte(data), &diff), ShouldBeNil)
+			So(diff.NewRecords[0].Run.ID, ShouldEqual, "b")
+
+			event, _ = client.next()
+			So(event, ShouldEqual, "leaderboard")
+		}
+
+		<-waiting
+		So(fetches, ShouldEqual, 2)
+
+		first.close()
+		second.close()
+
+		// polling stops once everybody is gone
+		stopped := false
+
+		for i := 0; i < 100 && !stopped; i++ {
+			server.mutex.Lock()
+			stopped = len(server.feeds) == 0
+			server.mutex.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		So(stopped, ShouldBeTrue)
+	})
+
+	Convey("Rejecting invalid keys", t, func() {
+		response, err := http.Get(httpServer.URL + "/game")
+		So(err, ShouldBeNil)
+		So(response.StatusCode, ShouldEqual, http.StatusNotFound)
+		response.Body.Close()
+	})
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// LocalRankedRun is a run of a leaderboard, together with its rank among the
+// runs of one country or region.
+type LocalRankedRun struct {
+	// the run and its rank on the whole leaderboard
+	RankedRun
+
+	// the rank among the local runs, starting at 1; runs that share their
+	// global rank share their local rank as well
+	LocalRank int
+}
+
+// LocalLeaderboard is the part of a leaderboard that has been done by runners
+// from one country or region.
+type LocalLeaderboard struct {
+	// the country code (e.g. "de") or region code (e.g. "us/ca")
+	Code string
+
+	// the international name of the country or region
+	Name string
+
+	// the runs, best first
+	Runs []LocalRankedRun
+}
+
+// CountryStats summarizes how the runners of a country do on a leaderboard.
+type CountryStats struct {
+	Code string
+	Name string
+
+	// number of distinct runners from the country
+	Runners int
+
+	// number of runs with at least one runner from the country
+	Runs int
+
+	// the best global rank of those runs
+	BestRank int
+
+	// number of those runs on the global podium (rank 3 or better)
+	Podiums int
+}
+
+// LocalLeaderboards splits a leaderboard by the location of its runners.
+//
+// A run counts for every country (and region) of its players, so a team of a
+// German and an American runner shows up on both the German and the American
+// leaderboard, but only once per country. Guests and users without a location
+// cannot be placed anywhere; runs done only by them end up in Unlocated.
+type LocalLeaderboards struct {
+	// the leaderboards by country code
+	Countries map[string]*LocalLeaderboard
+
+	// the leaderboards by region code, for users who have set a region
+	Regions map[string]*LocalLeaderboard
+
+	// the per-country stats, the country with the most runners first
+	Stats []CountryStats
+
+	// runs none of whose players has a known location
+	Unlocated []RankedRun
+}
+
+// data sources of LocalLeaderboards, replaced in tests
+var (
+	localBoards = LeaderboardKey.Fetch
+	localUsers  = UserByID
+)
+
+// LocalLeaderboards splits the leaderboard by the location of its runners (see
+// the LocalLeaderboards type). Embedded players are used if available;
+// otherwise the leaderboard is fetched again with the players embed, which
+// resolves all its runners in a single request. Only users still missing after
+// that (e.g. because the leaderboard changed in the meantime) are fetched one
+// by one.
+func (lb *Leaderboard) LocalLeaderboards() (*LocalLeaderboards, *Error) {
+	users := make(map[string]*User)
+
+	for _, user := range lb.Players().Users() {
+		users[user.ID] = user
+	}
+
+	missing, err := missingUsers(lb.Runs, users)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 && lb.PlayersData == nil {
+		options := &LeaderboardOptions{Platform: lb.Platform, Region: lb.Region, Timing: lb.Timing}
+
+		embedded, err := localBoards(lb.Key(), options, "players")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range embedded.Players().Users() {
+			users[user.ID] = user
+		}
+
+		if missing, err = missingUsers(lb.Runs, users); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, userID := range missing {
+		user, err := localUsers(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		users[userID] = user
+	}
+
+	return newLocalLeaderboards(lb.Runs, users), nil
+}
+
+// missingUsers returns the IDs of all user players of the runs that are not in
+// users yet, each ID only once.
+func missingUsers(runs []RankedRun, users map[string]*User) ([]string, *Error) {
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for idx := range runs {
+		links, err := runs[idx].Run.PlayerLinks()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			if len(link.ID) == 0 || seen[link.ID] {
+				continue
+			}
+
+			seen[link.ID] = true
+
+			if _, known := users[link.ID]; !known {
+				result = append(result, link.ID)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Country returns the leaderboard for a country code, or nil if no runner is
+// from that country.
+func (l *LocalLeaderboards) Country(code string) *LocalLeaderboard {
+	return l.Countries[strings.ToLower(code)]
+}
+
+// Region returns the leaderboard for a region code, or nil if no runner is
+// from that region.
+func (l *LocalLeaderboards) Region(code string) *LocalLeaderboard {
+	return l.Regions[strings.ToLower(code)]
+}
+
+// newLocalLeaderboards splits the ranked runs, with all user players being
+// available in users (unknown users are treated like guests).
+func newLocalLeaderboards(runs []RankedRun, users map[string]*User) *LocalLeaderboards {
+	result := &LocalLeaderboards{
+		Countries: make(map[string]*LocalLeaderboard),
+		Regions:   make(map[string]*LocalLeaderboard),
+	}
+
+	stats := make(map[string]*CountryStats)
+	runners := make(map[string]map[string]bool)
+
+	for _, ranked := range runs {
+		links, _ := ranked.Run.PlayerLinks()
+
+		countries := make(map[string]bool)
+		regions := make(map[string]bool)
+
+		for _, link := range links {
+			user, exists := users[link.ID]
+			if len(link.ID) == 0 || !exists || len(user.Location.Country.Code) == 0 {
+				continue
+			}
+
+			country := &user.Location.Country
+			code := strings.ToLower(country.Code)
+
+			if !countries[code] {
+				countries[code] = true
+				appendLocal(result.Countries, code, country.Names.International, ranked)
+
+				countryStats, exists := stats[code]
+				if !exists {
+					countryStats = &CountryStats{Code: code, Name: country.Names.International}
+					stats[code] = countryStats
+					runners[code] = make(map[string]bool)
+				}
+
+				countryStats.Runs++
+
+				if countryStats.BestRank == 0 || ranked.Rank < countryStats.BestRank {
+					countryStats.BestRank = ranked.Rank
+				}
+
+				if ranked.Rank <= 3 {
+					countryStats.Podiums++
+				}
+			}
+
+			runners[code][user.ID] = true
+
+			if region := user.Location.Region; region != nil && len(region.Code) > 0 {
+				regionCode := strings.ToLower(region.Code)
+
+				if !regions[regionCode] {
+					regions[regionCode] = true
+					appendLocal(result.Regions, regionCode, region.Names.International, ranked)
+				}
+			}
+		}
+
+		if len(countries) == 0 {
+			result.Unlocated = append(result.Unlocated, ranked)
+		}
+	}
+
+	for code, countryStats := range stats {
+		countryStats.Runners = len(runners[code])
+		result.Stats = append(result.Stats, *countryStats)
+	}
+
+	sort.Slice(result.Stats, func(i, j int) bool {
+		a, b := result.Stats[i], result.Stats[j]
+
+		if a.Runners != b.Runners {
+			return a.Runners > b.Runners
+		}
+
+		if a.BestRank != b.BestRank {
+			return a.BestRank < b.BestRank
+		}
+
+		return a.Code < b.Code
+	})
+
+	return result
+}
+
+// appendLocal adds a run to a local leaderboard, creating it if needed. Runs
+// must be appended in their global order.
+func appendLocal(boards map[string]*LocalLeaderboard, code string, name string, ranked RankedRun) {
+	board, exists := boards[code]
+	if !exists {
+		board = &LocalLeaderboard{Code: code, Name: name}
+		boards[code] = board
+	}
+
+	rank := len(board.Runs) + 1
+
+	if last := len(board.Runs) - 1; last >= 0 && board.Runs[last].Rank == ranked.Rank {
+		rank = board.Runs[last].LocalRank
+	}
+
+	board.Runs = append(board.Runs, LocalRankedRun{ranked, rank})
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testLocalLeaderboard = `{"game": "game", "category": "cat", "runs": [
+	{"place": 1, "run": {"id": "a", "times": {"primary_t": 50}, "players": [{"rel": "user", "id": "us1"}]}},
+	{"place": 2, "run": {"id": "b", "times": {"primary_t": 60}, "players": [{"rel": "user", "id": "de1"}, {"rel": "user", "id": "us2"}]}},
+	{"place": 2, "run": {"id": "c", "times": {"primary_t": 60}, "players": [{"rel": "user", "id": "de2"}]}},
+	{"place": 4, "run": {"id": "d", "times": {"primary_t": 70}, "players": [{"rel": "user", "id": "de1"}, {"rel": "user", "id": "de2"}]}},
+	{"place": 5, "run": {"id": "guest", "times": {"primary_t": 80}, "players": [{"rel": "guest", "name": "Bob"}]}},
+	{"place": 6, "run": {"id": "e", "times": {"primary_t": 90}, "players": [{"rel": "user", "id": "nil"}, {"rel": "user", "id": "unknown"}]}}
+]}`
+
+const testLocalPlayers = `{"players": {"data": [
+	{"rel": "user", "id": "de1", "location": {"country": {"code": "de", "names": {"international": "Country de"}}}},
+	{"rel": "user", "id": "de2", "location": {"country": {"code": "DE", "names": {"international": "Country DE"}}}},
+	{"rel": "user", "id": "us1", "location": {"country": {"code": "us", "names": {"international": "Country us"}}, "region": {"code": "us/ca"}}},
+	{"rel": "user", "id": "us2", "location": {"country": {"code": "us", "names": {"international": "Country us"}}, "region": {"code": "us/ny"}}},
+	{"rel": "user", "id": "nil"},
+	{"rel": "guest", "name": "Bob"}
+]}}`
+
+func TestLocalLeaderboards(t *testing.T) {
+	users := make(map[string]*User)
+
+	for _, user := range decodeLeaderboard(testLocalPlayers).Players().Users() {
+		users[user.ID] = user
+	}
+
+	local := newLocalLeaderboards(decodeLeaderboard(testLocalLeaderboard).Runs, users)
+
+	localIDs := func(board *LocalLeaderboard) ([]string, []int) {
+		var ids []string
+		var ranks []int
+
+		for _, run := range board.Runs {
+			ids = append(ids, run.Run.ID)
+			ranks = append(ranks, run.LocalRank)
+		}
+
+		return ids, ranks
+	}
+
+	Convey("Runs are split by country", t, func() {
+		So(len(local.Countries), ShouldEqual, 2)
+
+		ids, ranks := localIDs(local.Country("DE"))
+		So(ids, ShouldResemble, []string{"b", "c", "d"})
+		So(ranks, ShouldResemble, []int{1, 1, 3})
+		So(local.Country("de").Name, ShouldEqual, "Country de")
+
+		ids, ranks = localIDs(local.Country("us"))
+		So(ids, ShouldResemble, []string{"a", "b"})
+		So(ranks, ShouldResemble, []int{1, 2})
+		So(local.Country("us").Runs[1].Rank, ShouldEqual, 2)
+
+		So(local.Country("fr"), ShouldBeNil)
+	})
+
+	Convey("Runs are split by region", t, func() {
+		ids, _ := localIDs(local.Region("us/ca"))
+		So(ids, ShouldResemble, []string{"a"})
+
+		ids, _ = localIDs(local.Region("us/ny"))
+		So(ids, ShouldResemble, []string{"b"})
+	})
+
+	Convey("Runs by guests and unlocated users are kept aside", t, func() {
+		So(len(local.Unlocated), ShouldEqual, 2)
+		So(local.Unlocated[0].Run.ID, ShouldEqual, "guest")
+		So(local.Unlocated[1].Run.ID, ShouldEqual, "e")
+	})
+
+	Convey("Stats are computed per country", t, func() {
+		So(local.Stats, ShouldResemble, []CountryStats{
+			{Code: "us", Name: "Country us", Runners: 2, Runs: 2, BestRank: 1, Podiums: 2},
+			{Code: "de", Name: "Country de", Runners: 2, Runs: 3, BestRank: 2, Podiums: 2},
+		})
+	})
+
+	Convey("Runners are looked up with a single embedded fetch", t, func() {
+		defer func(boards func(LeaderboardKey, *LeaderboardOptions, string) (*Leaderboard, *Error), byID func(string) (*User, *Error)) {
+			localBoards, localUsers = boards, byID
+		}(localBoards, localUsers)
+
+		var embeds []string
+		var fetched []string
+
+		localBoards = func(key LeaderboardKey, options *LeaderboardOptions, embed string) (*Leaderboard, *Error) {
+			embeds = append(embeds, embed)
+			return decodeLeaderboard(testLocalPlayers), nil
+		}
+
+		localUsers = func(id string) (*User, *Error) {
+			fetched = append(fetched, id)
+			return &User{ID: id}, nil
+		}
+
+		embedded, err := decodeLeaderboard(testLocalLeaderboard).LocalLeaderboards()
+		So(err, ShouldBeNil)
+		So(embeds, ShouldResemble, []string{"players"})
+		So(fetched, ShouldResemble, []string{"unknown"})
+
+		ids, ranks := localIDs(embedded.Country("de"))
+		So(ids, ShouldResemble, []string{"b", "c", "d"})
+		So(ranks, ShouldResemble, []int{1, 1, 3})
+	})
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import "container/heap"
+
+// RunLessFunc reports whether run a should come before run b. It defines the
+// order of a sorted stream of runs.
+type RunLessFunc func(a, b *Run) bool
+
+// RunsBySubmitted orders runs by their submission date, oldest first. Runs
+// without a submission date come first. It matches sorting a collection with
+// &Sorting{"submitted", Ascending}.
+func RunsBySubmitted(a, b *Run) bool {
+	if a.Submitted == nil || b.Submitted == nil {
+		return a.Submitted == nil && b.Submitted != nil
+	}
+
+	return a.Submitted.Before(*b.Submitted)
+}
+
+// RunsByDate orders runs by the date they were done on, oldest first. Runs
+// without a date come first. It matches sorting a collection with
+// &Sorting{"date", Ascending}.
+func RunsByDate(a, b *Run) bool {
+	if a.Date == nil || b.Date == nil {
+		return a.Date == nil && b.Date != nil
+	}
+
+	return a.Date.Before(b.Date.Time)
+}
+
+// Reverse turns an ascending order into a descending one, e.g. for merging
+// collections that have been sorted with Descending.
+func (less RunLessFunc) Reverse() RunLessFunc {
+	return func(a, b *Run) bool {
+		return less(b, a)
+	}
+}
+
+// MergeRuns combines multiple run collections, each of which must already be
+// sorted according to less, into a single stream that is sorted the same way.
+// All collections are consumed concurrently, i.e. further pages are fetched in
+// the background while the merged stream is being read. A run that is part of
+// more than one collection is only returned once.
+func MergeRuns(less RunLessFunc, collections ...*RunCollection) *RunMerger {
+	m := &RunMerger{
+		output:     make(chan *Run),
+		killSwitch: make(chan struct{}),
+		done:       make(chan struct{}),
+		less:       less,
+	}
+
+	for _, collection := range collections {
+		if collection != nil {
+			m.inputs = append(m.inputs, collection.Iterator())
+		}
+	}
+
+	go m.work()
+
+	return m
+}
+
+// RunMerger is an ordered stream of runs, merged from multiple collections.
+type RunMerger struct {
+	output     chan *Run
+	killSwitch chan struct{}
+	done       chan struct{}
+	less       RunLessFunc
+	inputs     []RunIterator
+}
+
+// Output returns a channel that can be used to read all runs from the merger.
+func (m *RunMerger) Output() <-chan *Run {
+	return m.output
+}
+
+// Stop interrupts the merger and all underlying iterators. After calling
+// this, the merger returns no more runs and becomes unusable.
+func (m *RunMerger) Stop() {
+	close(m.killSwitch)
+	<-m.done
+}
+
+// Walk applies a function to all runs in the stream, in order. If the
+// function returns false, the merger will be stopped.
+func (m *RunMerger) Walk(f RunWalkerFunc) {
+	for run := range m.Output() {
+		if !f(run) {
+			m.Stop()
+		}
+	}
+}
+
+// work is the goroutine that keeps the head of every input in a heap and
+// always emits the smallest one.
+func (m *RunMerger) work() {
+	defer close(m.done)
+	defer close(m.output)
+
+	defer func() {
+		for idx := range m.inputs {
+			m.inputs[idx].Stop()
+		}
+	}()
+
+	heads := &runHeap{less: m.less}
+	seen := make(map[string]bool)
+
+	// pulls the next run from an input, if it has one left
+	pull := func(source int) bool {
+		select {
+		case <-m.killSwitch:
+			return false
+
+		case run, okay := <-m.inputs[source].Output():
+			if okay {
+				heap.Push(heads, runHeapItem{run, source})
+			}
+
+			return true
+		}
+	}
+
+	for idx := range m.inputs {
+		if !pull(idx) {
+			return
+		}
+	}
+
+	for heads.Len() > 0 {
+		head := heap.Pop(heads).(runHeapItem)
+
+		if !seen[head.run.ID] {
+			seen[head.run.ID] = true
+
+			select {
+			case <-m.killSwitch:
+				return
+
+			case m.output <- head.run:
+			}
+		}
+
+		if !pull(head.source) {
+			return
+		}
+	}
+}
+
+// runHeapItem is a run together with the index of the input it came from.
+type runHeapItem struct {
+	run    *Run
+	source int
+}
+
+// runHeap is a min-heap of runs, implementing heap.Interface. Runs that are
+// equal are ordered by their input, so merging is deterministic.
+type runHeap struct {
+	items []runHeapItem
+	less  RunLessFunc
+}
+
+func (h *runHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+
+	if h.less(a.run, b.run) {
+		return true
+	}
+
+	if h.less(b.run, a.run) {
+		return false
+	}
+
+	return a.source < b.source
+}
+
+func (h *runHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(runHeapItem))
+}
+
+func (h *runHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+
+	return last
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// submittedRuns creates a collection of runs with the given IDs, submitted n
+// minutes after a fixed point in time.
+func submittedRuns(runs map[string]int, order ...string) *RunCollection {
+	base := time.Date(2015, 8, 1, 12, 0, 0, 0, time.UTC)
+	result := &RunCollection{}
+
+	for _, id := range order {
+		submitted := base.Add(time.Duration(runs[id]) * time.Minute)
+		result.Data = append(result.Data, Run{ID: id, Submitted: &submitted})
+	}
+
+	return result
+}
+
+func TestMergeRuns(t *testing.T) {
+	minutes := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7}
+
+	collect := func(m *RunMerger) []string {
+		var result []string
+
+		m.Walk(func(r *Run) bool {
+			result = append(result, r.ID)
+			return true
+		})
+
+		return result
+	}
+
+	Convey("Merging sorted collections", t, func() {
+		first := submittedRuns(minutes, "a", "d", "e")
+		second := submittedRuns(minutes, "b", "c", "g")
+		third := submittedRuns(minutes, "f")
+
+		merged := collect(MergeRuns(RunsBySubmitted, first, second, third, &RunCollection{}))
+		So(merged, ShouldResemble, []string{"a", "b", "c", "d", "e", "f", "g"})
+	})
+
+	Convey("Runs in multiple collections are only returned once", t, func() {
+		first := submittedRuns(minutes, "a", "c", "e")
+		second := submittedRuns(minutes, "a", "b", "c", "f")
+
+		merged := collect(MergeRuns(RunsBySubmitted, first, second))
+		So(merged, ShouldResemble, []string{"a", "b", "c", "e", "f"})
+	})
+
+	Convey("Merging descending collections", t, func() {
+		first := submittedRuns(minutes, "g", "c", "a")
+		second := submittedRuns(minutes, "f", "e", "b")
+
+		merged := collect(MergeRuns(RunLessFunc(RunsBySubmitted).Reverse(), first, second))
+		So(merged, ShouldResemble, []string{"g", "f", "e", "c", "b", "a"})
+	})
+
+	Convey("Stopping a merge early", t, func() {
+		first := submittedRuns(minutes, "a", "c", "e")
+		second := submittedRuns(minutes, "b", "d", "f")
+
+		var merged []string
+
+		MergeRuns(RunsBySubmitted, first, second).Walk(func(r *Run) bool {
+			merged = append(merged, r.ID)
+			return len(merged) < 3
+		})
+
+		So(merged, ShouldResemble, []string{"a", "b", "c"})
+	})
+
+	Convey("Runs without dates come first", t, func() {
+		undated := &RunCollection{Data: []Run{{ID: "x"}}}
+		first := submittedRuns(minutes, "a", "b")
+
+		merged := collect(MergeRuns(RunsBySubmitted, first, undated))
+		So(merged, ShouldResemble, []string{"x", "a", "b"})
+	})
+}
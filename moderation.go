@@ -0,0 +1,153 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+// moderationEmbeds are the embeds for runs in a moderation queue, so that
+// they can be displayed without further requests.
+const moderationEmbeds = "game,category,level,players"
+
+// ModerationQueue is the list of runs waiting to be examined by a moderator,
+// across all of their games.
+type ModerationQueue struct {
+	// the moderator's user ID
+	Moderator string
+
+	// all games the user moderates, either directly or via a series
+	Games []*Game
+
+	// the unverified runs, with their game, category, level and players
+	// embedded; the oldest submission comes first
+	Runs []*Run
+
+	// number of unverified runs per game ID; games without any pending runs
+	// are included with a count of 0
+	Counts map[string]int
+}
+
+// ModerationQueueFor fetches the moderation queue of a user. It includes the
+// games the user moderates directly and all games of the series the user
+// moderates. This takes a few requests for the games and one (or more, if
+// there are many pending runs) per game.
+func ModerationQueueFor(userID string) (*ModerationQueue, *Error) {
+	games, err := moderatedGames(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var collections []*RunCollection
+
+	for _, game := range games {
+		filter := RunFilter{Game: game.ID, Status: "new"}
+
+		runs, err := Runs(&filter, &Sorting{"submitted", Ascending}, &Cursor{0, maxPageSize}, moderationEmbeds)
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, runs)
+	}
+
+	var runs []*Run
+
+	MergeRuns(RunsBySubmitted, collections...).Walk(func(run *Run) bool {
+		runs = append(runs, run)
+		return true
+	})
+
+	return newModerationQueue(userID, games, runs), nil
+}
+
+// ModerationQueue fetches the moderation queue of the user; see
+// ModerationQueueFor().
+func (u *User) ModerationQueue() (*ModerationQueue, *Error) {
+	return ModerationQueueFor(u.ID)
+}
+
+// Oldest returns the run that has been waiting the longest, or nil if the
+// queue is empty.
+func (q *ModerationQueue) Oldest() *Run {
+	if len(q.Runs) == 0 {
+		return nil
+	}
+
+	return q.Runs[0]
+}
+
+// GameRuns returns the pending runs of a single game, oldest first.
+func (q *ModerationQueue) GameRuns(gameID string) []*Run {
+	var result []*Run
+
+	for _, run := range q.Runs {
+		if recastToID(run.GameData) == gameID {
+			result = append(result, run)
+		}
+	}
+
+	return result
+}
+
+// moderatedGames collects the games a user moderates directly and via series,
+// without duplicates.
+func moderatedGames(userID string) ([]*Game, *Error) {
+	var result []*Game
+	seen := make(map[string]bool)
+
+	collect := func(game *Game) bool {
+		if !seen[game.ID] {
+			seen[game.ID] = true
+			result = append(result, game)
+		}
+
+		return true
+	}
+
+	direct, err := Games(&GameFilter{Moderator: userID}, nil, &Cursor{0, maxPageSize}, NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	direct.Walk(collect)
+
+	series, err := ManySeries(&SeriesFilter{Moderator: userID}, nil, &Cursor{0, maxPageSize}, NoEmbeds)
+	if err != nil {
+		return nil, err
+	}
+
+	var allSeries []*Series
+
+	series.Walk(func(s *Series) bool {
+		allSeries = append(allSeries, s)
+		return true
+	})
+
+	for _, s := range allSeries {
+		games, err := s.Games(nil, nil, NoEmbeds)
+		if err != nil {
+			return nil, err
+		}
+
+		games.Walk(collect)
+	}
+
+	return result, nil
+}
+
+// newModerationQueue assembles a queue from already sorted runs.
+func newModerationQueue(userID string, games []*Game, runs []*Run) *ModerationQueue {
+	queue := &ModerationQueue{
+		Moderator: userID,
+		Games:     games,
+		Runs:      runs,
+		Counts:    make(map[string]int),
+	}
+
+	for _, game := range games {
+		queue.Counts[game.ID] = 0
+	}
+
+	for _, run := range runs {
+		queue.Counts[recastToID(run.GameData)]++
+	}
+
+	return queue
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestModerationQueue(t *testing.T) {
+	Convey("Assembling a moderation queue", t, func() {
+		minutes := map[string]int{"a": 1, "b": 2, "c": 3}
+		smw := submittedRuns(minutes, "a", "c")
+		sm64 := submittedRuns(minutes, "b")
+
+		for idx := range smw.Data {
+			smw.Data[idx].GameData = map[string]interface{}{"data": map[string]interface{}{"id": "smw"}}
+		}
+
+		sm64.Data[0].GameData = "sm64"
+
+		var runs []*Run
+
+		MergeRuns(RunsBySubmitted, smw, sm64).Walk(func(run *Run) bool {
+			runs = append(runs, run)
+			return true
+		})
+
+		games := []*Game{{ID: "smw"}, {ID: "sm64"}, {ID: "oot"}}
+		queue := newModerationQueue("me", games, runs)
+
+		So(queue.Moderator, ShouldEqual, "me")
+		So(len(queue.Runs), ShouldEqual, 3)
+		So(queue.Oldest().ID, ShouldEqual, "a")
+		So(queue.Counts, ShouldResemble, map[string]int{"smw": 2, "sm64": 1, "oot": 0})
+		So(len(queue.GameRuns("smw")), ShouldEqual, 2)
+		So(queue.GameRuns("smw")[1].ID, ShouldEqual, "c")
+		So(queue.GameRuns("oot"), ShouldBeNil)
+	})
+
+	Convey("Empty moderation queues", t, func() {
+		queue := newModerationQueue("me", nil, nil)
+		So(queue.Oldest(), ShouldBeNil)
+		So(len(queue.Counts), ShouldEqual, 0)
+	})
+}
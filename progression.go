@@ -0,0 +1,162 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"time"
+)
+
+// WorldRecord is a run that improved the world record of a leaderboard at the
+// time it was done.
+type WorldRecord struct {
+	// the record-setting run
+	Run Run
+
+	// the run's time, measured with the timing method of the progression
+	Time Duration
+
+	// the day the record was set (the run's date, or the day it was submitted
+	// if the run has no date)
+	Date time.Time
+
+	// how long the record stood until it was beaten; for the current record,
+	// this is the time until the progression was computed
+	Reign time.Duration
+
+	// how much faster this record is compared to the previous one; zero for
+	// the very first record
+	Improvement Duration
+}
+
+// WRProgression returns the chronological list of runs that have set a new
+// world record for a leaderboard, i.e. for a category and (for individual
+// levels) a level. Only verified runs are taken into account. From options,
+// only Values (to select a sub-category) and Timing are used; the timing
+// method defaults to the game's default timing method. If game is nil, it
+// is fetched automatically.
+func WRProgression(game *Game, cat *Category, level *Level, options *LeaderboardOptions) ([]WorldRecord, *Error) {
+	if cat == nil {
+		return nil, &Error{"", "", ErrorBadLogic, "No category given."}
+	}
+
+	if level == nil && cat.Type != "per-game" {
+		return nil, &Error{"", "", ErrorBadLogic, "The given category is not a full-game category."}
+	}
+
+	if level != nil && cat.Type != "per-level" {
+		return nil, &Error{"", "", ErrorBadLogic, "The given category is not a individual-level category."}
+	}
+
+	if game == nil {
+		var err *Error
+
+		game, err = cat.Game("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options == nil {
+		options = &LeaderboardOptions{}
+	}
+
+	method := options.Timing
+	if len(method) == 0 {
+		method = game.Ruleset.DefaultTime
+	}
+
+	filter := &RunFilter{
+		Game:     game.ID,
+		Category: cat.ID,
+		Status:   "verified",
+	}
+
+	if level != nil {
+		filter.Level = level.ID
+	}
+
+	var runs []*Run
+
+	err := WalkAllRuns(filter, NoEmbeds, func(run *Run) bool {
+		runs = append(runs, run)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return wrProgression(runs, method, options.Values, time.Now()), nil
+}
+
+// wrProgression computes the record progression from a list of runs, which
+// can be in any order. now is used to compute the reign of the current record.
+func wrProgression(runs []*Run, method TimingMethod, values map[string]string, now time.Time) []WorldRecord {
+	var candidates []*Run
+
+	for _, run := range runs {
+		if run.timeFor(method) != nil && run.matchesValues(values) && !runDay(run).IsZero() {
+			candidates = append(candidates, run)
+		}
+	}
+
+	// oldest first; runs on the same day are ordered by their submission
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := runDay(candidates[i]), runDay(candidates[j])
+
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+
+		return RunsBySubmitted(candidates[i], candidates[j])
+	})
+
+	var result []WorldRecord
+
+	for _, run := range candidates {
+		runTime := run.timeFor(method)
+
+		if len(result) > 0 {
+			previous := &result[len(result)-1]
+			if !runTime.Less(previous.Time) {
+				continue
+			}
+
+			previous.Reign = runDay(run).Sub(previous.Date)
+		}
+
+		record := WorldRecord{
+			Run:  *run,
+			Time: *runTime,
+			Date: runDay(run),
+		}
+
+		if len(result) > 0 {
+			record.Improvement = Duration{result[len(result)-1].Time.Duration - runTime.Duration}
+		}
+
+		result = append(result, record)
+	}
+
+	if len(result) > 0 {
+		current := &result[len(result)-1]
+		current.Reign = now.Sub(current.Date)
+	}
+
+	return result
+}
+
+// runDay returns the day a run was done on or, if that is unknown, the time
+// it was submitted. If both are missing, the zero time is returned.
+func runDay(run *Run) time.Time {
+	if run.Date != nil {
+		return run.Date.Time
+	}
+
+	if run.Submitted != nil {
+		return *run.Submitted
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// timedRun creates a run done on the given day with a realtime of the given
+// number of seconds and the given difficulty value.
+func timedRun(id string, day string, seconds int, difficulty string) *Run {
+	date, _ := time.Parse(dateLayout, day)
+
+	run := &Run{ID: id, Date: &Date{date}, Values: map[string]string{"difficulty": difficulty}}
+	run.Times.Realtime = &Duration{time.Duration(seconds) * time.Second}
+
+	return run
+}
+
+func TestWRProgression(t *testing.T) {
+	now := time.Date(2015, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	runs := []*Run{
+		timedRun("slow", "2015-01-10", 600, "easy"),
+		timedRun("first", "2015-01-01", 620, "easy"),
+		timedRun("second", "2015-02-01", 590, "easy"),
+		timedRun("tie", "2015-03-01", 590, "easy"),
+		timedRun("hard", "2015-03-05", 500, "hard"),
+		timedRun("third", "2015-06-01", 580, "easy"),
+	}
+
+	Convey("Computing a record progression", t, func() {
+		records := wrProgression(runs, TimingRealtime, map[string]string{"difficulty": "easy"}, now)
+		So(records, ShouldHaveLength, 4)
+
+		So(records[0].Run.ID, ShouldEqual, "first")
+		So(records[0].Improvement.Duration, ShouldEqual, 0)
+		So(records[0].Reign, ShouldEqual, 9*24*time.Hour)
+
+		So(records[1].Run.ID, ShouldEqual, "slow")
+		So(records[1].Improvement.Duration, ShouldEqual, 20*time.Second)
+
+		So(records[2].Run.ID, ShouldEqual, "second")
+		So(records[2].Reign, ShouldEqual, records[3].Date.Sub(records[2].Date))
+
+		So(records[3].Run.ID, ShouldEqual, "third")
+		So(records[3].Time.Duration, ShouldEqual, 580*time.Second)
+		So(records[3].Reign, ShouldEqual, now.Sub(records[3].Date))
+	})
+
+	Convey("Sub-categories are respected", t, func() {
+		records := wrProgression(runs, TimingRealtime, map[string]string{"difficulty": "hard"}, now)
+		So(records, ShouldHaveLength, 1)
+		So(records[0].Run.ID, ShouldEqual, "hard")
+	})
+
+	Convey("Runs without a time for the timing method are ignored", t, func() {
+		records := wrProgression(runs, TimingIngameTime, nil, now)
+		So(records, ShouldBeEmpty)
+	})
+}
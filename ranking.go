@@ -0,0 +1,167 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// RankRuns computes a leaderboard from a list of runs, without asking the
+// API. The options have the same meaning as for FullGameLeaderboard():
+//
+//   - Timing selects the time to compare; if empty, the primary time is used.
+//   - Emulators and VideoOnly, if set to Yes, only keep emulated runs or runs
+//     with a video; Emulators set to No only keeps runs on real hardware.
+//   - Platform, Region and Values only keep runs with matching settings.
+//   - Date only keeps runs done on or before that day.
+//   - Top only keeps runs with a rank better than or equal to it.
+//
+// Runs that have a status other than "verified" are ignored (runs without any
+// status are kept, to allow for what-if analysis). For every player, or every
+// team of players, only the best run is ranked. Runs with the same time share
+// a rank and the following rank is skipped (1, 2, 2, 4); among them, the run
+// done first is listed first. An error is returned if options.Date is not a
+// valid date.
+func RankRuns(runs []*Run, options *LeaderboardOptions) ([]RankedRun, *Error) {
+	if options == nil {
+		options = &LeaderboardOptions{}
+	}
+
+	var cutoff time.Time
+
+	if len(options.Date) > 0 {
+		parsed, err := time.Parse(dateLayout, options.Date)
+		if err != nil {
+			return nil, &Error{"", "", ErrorBadLogic, "Invalid date given, must be formatted as " + dateLayout + "."}
+		}
+
+		cutoff = parsed
+	}
+
+	// find the best run for every player/team
+	best := make(map[string]*Run)
+	var teams []string
+
+	for _, run := range runs {
+		if !rankable(run, options, cutoff) {
+			continue
+		}
+
+		team := teamKey(run)
+		current, exists := best[team]
+
+		if !exists {
+			teams = append(teams, team)
+		}
+
+		if !exists || rankedBefore(run, current, options.Timing) {
+			best[team] = run
+		}
+	}
+
+	candidates := make([]*Run, 0, len(teams))
+	for _, team := range teams {
+		candidates = append(candidates, best[team])
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rankedBefore(candidates[i], candidates[j], options.Timing)
+	})
+
+	// assign ranks, sharing them among ties
+	var result []RankedRun
+
+	for idx, run := range candidates {
+		rank := idx + 1
+
+		if idx > 0 && rankingTime(run, options.Timing).Equal(*rankingTime(candidates[idx-1], options.Timing)) {
+			rank = result[idx-1].Rank
+		}
+
+		if options.Top > 0 && rank > options.Top {
+			break
+		}
+
+		result = append(result, RankedRun{*run, rank})
+	}
+
+	return result, nil
+}
+
+// rankable checks if a run is eligible for a leaderboard with the given options.
+func rankable(run *Run, options *LeaderboardOptions, cutoff time.Time) bool {
+	if len(run.Status.Status) > 0 && run.Status.Status != "verified" {
+		return false
+	}
+
+	if rankingTime(run, options.Timing) == nil {
+		return false
+	}
+
+	if (options.Emulators == Yes && !run.System.Emulated) || (options.Emulators == No && run.System.Emulated) {
+		return false
+	}
+
+	if options.VideoOnly == Yes && len(run.Videos.Links) == 0 {
+		return false
+	}
+
+	if (len(options.Platform) > 0 && run.System.Platform != options.Platform) ||
+		(len(options.Region) > 0 && run.System.Region != options.Region) {
+		return false
+	}
+
+	if !run.matchesValues(options.Values) {
+		return false
+	}
+
+	if !cutoff.IsZero() && (run.Date == nil || run.Date.After(cutoff)) {
+		return false
+	}
+
+	return true
+}
+
+// rankingTime returns the time a run is ranked by.
+func rankingTime(run *Run, method TimingMethod) *Duration {
+	if len(method) == 0 {
+		return run.Times.Primary
+	}
+
+	return run.timeFor(method)
+}
+
+// rankedBefore checks if run a is ranked before run b, i.e. it is faster or
+// equally fast, but was done earlier.
+func rankedBefore(a *Run, b *Run, method TimingMethod) bool {
+	if cmp := CompareTimes(rankingTime(a, method), rankingTime(b, method)); cmp != 0 {
+		return cmp < 0
+	}
+
+	return runDay(a).Before(runDay(b))
+}
+
+// teamKey identifies the player or the team of players that did a run. Runs
+// without any players are considered to be done by a team of their own.
+func teamKey(run *Run) string {
+	links, _ := run.PlayerLinks()
+	if len(links) == 0 {
+		return "run:" + run.ID
+	}
+
+	var players []string
+
+	for _, link := range links {
+		if link.Relation == "guest" {
+			players = append(players, "guest:"+strings.ToLower(link.Name))
+		} else {
+			players = append(players, "user:"+link.ID)
+		}
+	}
+
+	sort.Strings(players)
+
+	return strings.Join(players, ",")
+}
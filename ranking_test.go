@@ -0,0 +1,112 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// playedRun creates a verified run by the given users, done on the given day,
+// with a primary and realtime time of the given number of seconds.
+func playedRun(id string, day string, seconds int, users ...string) *Run {
+	date, _ := time.Parse(dateLayout, day)
+
+	run := &Run{ID: id, Date: &Date{date}}
+	run.Status.Status = "verified"
+	run.Times.Primary = &Duration{time.Duration(seconds) * time.Second}
+	run.Times.Realtime = run.Times.Primary
+
+	var players []interface{}
+	for _, user := range users {
+		players = append(players, map[string]interface{}{"rel": "user", "id": user})
+	}
+
+	run.PlayersData = players
+
+	return run
+}
+
+func TestRankRuns(t *testing.T) {
+	ids := func(ranked []RankedRun) []string {
+		var result []string
+
+		for _, r := range ranked {
+			result = append(result, r.Run.ID)
+		}
+
+		return result
+	}
+
+	ranks := func(ranked []RankedRun) []int {
+		var result []int
+
+		for _, r := range ranked {
+			result = append(result, r.Rank)
+		}
+
+		return result
+	}
+
+	runs := []*Run{
+		playedRun("a1", "2015-01-01", 120, "alice"),
+		playedRun("a2", "2015-02-01", 100, "alice"),
+		playedRun("b1", "2015-01-15", 110, "bob"),
+		playedRun("c1", "2015-01-10", 110, "carol"),
+		playedRun("d1", "2015-03-01", 130, "dave"),
+		playedRun("t1", "2015-01-20", 90, "bob", "carol"),
+		playedRun("t2", "2015-01-21", 95, "carol", "bob"),
+	}
+
+	rejected := playedRun("x1", "2015-01-01", 10, "eve")
+	rejected.Status.Status = "rejected"
+	runs = append(runs, rejected)
+
+	Convey("Ranking runs", t, func() {
+		ranked, err := RankRuns(runs, nil)
+		So(err, ShouldBeNil)
+		So(ids(ranked), ShouldResemble, []string{"t1", "a2", "c1", "b1", "d1"})
+		So(ranks(ranked), ShouldResemble, []int{1, 2, 3, 3, 5})
+	})
+
+	Convey("Limiting the ranks", t, func() {
+		ranked, _ := RankRuns(runs, &LeaderboardOptions{Top: 3})
+		So(ids(ranked), ShouldResemble, []string{"t1", "a2", "c1", "b1"})
+	})
+
+	Convey("Applying a date cutoff", t, func() {
+		ranked, err := RankRuns(runs, &LeaderboardOptions{Date: "2015-01-15"})
+		So(err, ShouldBeNil)
+		So(ids(ranked), ShouldResemble, []string{"c1", "b1", "a1"})
+
+		_, err = RankRuns(runs, &LeaderboardOptions{Date: "yesterday"})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Filtering by system and values", t, func() {
+		emulated := playedRun("e1", "2015-01-01", 50, "frank")
+		emulated.System.Emulated = true
+		emulated.System.Platform = "snes"
+		emulated.Values = map[string]string{"var": "val"}
+		emulated.Videos.Links = []Link{{"", "https://youtu.be/abc"}}
+
+		all := append([]*Run{emulated}, runs...)
+
+		ranked, _ := RankRuns(all, &LeaderboardOptions{Emulators: No})
+		So(ranked[0].Run.ID, ShouldEqual, "t1")
+
+		ranked, _ = RankRuns(all, &LeaderboardOptions{Emulators: Yes})
+		So(ids(ranked), ShouldResemble, []string{"e1"})
+
+		ranked, _ = RankRuns(all, &LeaderboardOptions{VideoOnly: Yes})
+		So(ids(ranked), ShouldResemble, []string{"e1"})
+
+		ranked, _ = RankRuns(all, &LeaderboardOptions{Platform: "snes", Values: map[string]string{"var": "val"}})
+		So(ids(ranked), ShouldResemble, []string{"e1"})
+
+		ranked, _ = RankRuns(all, &LeaderboardOptions{Timing: TimingIngameTime})
+		So(ranked, ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,609 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+// Package render turns leaderboards into tables, either as aligned plain
+// text, as Markdown or as an HTML fragment.
+package render
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sgt-kabukiman/srapi"
+)
+
+// Embeds are the embeds a leaderboard should be fetched with, so that it can
+// be rendered without showing raw IDs.
+const Embeds = "game,players,variables,platforms,regions"
+
+// Column is a single column of a rendered leaderboard.
+type Column string
+
+const (
+	// RankColumn shows the run's rank.
+	RankColumn Column = "rank"
+
+	// PlayerColumn shows the runners' names.
+	PlayerColumn Column = "player"
+
+	// TimeColumn shows the primary time.
+	TimeColumn Column = "time"
+
+	// RealtimeColumn shows the realtime.
+	RealtimeColumn Column = Column(srapi.TimingRealtime)
+
+	// RealtimeWithoutLoadsColumn shows the realtime without loads.
+	RealtimeWithoutLoadsColumn Column = Column(srapi.TimingRealtimeWithoutLoads)
+
+	// IngameTimeColumn shows the in-game time.
+	IngameTimeColumn Column = Column(srapi.TimingIngameTime)
+
+	// DateColumn shows the date the run was done.
+	DateColumn Column = "date"
+
+	// PlatformColumn shows the platform's name.
+	PlatformColumn Column = "platform"
+
+	// RegionColumn shows the region's name.
+	RegionColumn Column = "region"
+
+	// EmulatedColumn shows whether the run was done on an emulator.
+	EmulatedColumn Column = "emulated"
+
+	// VideoColumn links to the run's first video.
+	VideoColumn Column = "video"
+
+	// ValuesColumn shows the run's variable values, except for those the
+	// leaderboard has been filtered by.
+	ValuesColumn Column = "values"
+)
+
+// DefaultColumns are used if no columns are given.
+var DefaultColumns = []Column{RankColumn, PlayerColumn, TimeColumn, DateColumn, PlatformColumn, VideoColumn}
+
+// headers are the column titles.
+var headers = map[Column]string{
+	RankColumn:                 "#",
+	PlayerColumn:               "Player",
+	TimeColumn:                 "Time",
+	RealtimeColumn:             "RTA",
+	RealtimeWithoutLoadsColumn: "RTA (no loads)",
+	IngameTimeColumn:           "IGT",
+	DateColumn:                 "Date",
+	PlatformColumn:             "Platform",
+	RegionColumn:               "Region",
+	EmulatedColumn:             "Emulated",
+	VideoColumn:                "Video",
+	ValuesColumn:               "Values",
+}
+
+// Span is a piece of a cell, e.g. one of several runners.
+type Span struct {
+	Text string
+
+	// optional link target; only http(s) URLs are rendered as links
+	Link string
+
+	// the user's name style, nil for guests and anything that is not a name
+	Color     *srapi.NameColor
+	ColorFrom *srapi.NameColor
+	ColorTo   *srapi.NameColor
+}
+
+// Cell is a single table cell. Multiple spans are separated by ", ".
+type Cell struct {
+	Spans []Span
+
+	// true for numbers and times
+	AlignRight bool
+}
+
+// Text returns the cell's plain text.
+func (c *Cell) Text() string {
+	var parts []string
+
+	for _, span := range c.Spans {
+		parts = append(parts, span.Text)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Table is a leaderboard, prepared for rendering.
+type Table struct {
+	Columns []Column
+	Headers []string
+	Rows    [][]Cell
+
+	// use the colors meant for dark backgrounds in HTML
+	Dark bool
+}
+
+// NewTable prepares a leaderboard with the given columns (DefaultColumns if
+// none are given). Only embedded data is used; this never makes a request.
+// Players, platforms and regions that have not been embedded are shown by
+// their IDs, and times are shown with milliseconds only if needed unless the
+// game has been embedded.
+func NewTable(lb *srapi.Leaderboard, columns ...Column) *Table {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+
+	ctx := newContext(lb)
+	table := &Table{Columns: columns}
+
+	for _, column := range columns {
+		header, exists := headers[column]
+		if !exists {
+			header = string(column)
+		}
+
+		table.Headers = append(table.Headers, header)
+	}
+
+	for idx := range lb.Runs {
+		ranked := &lb.Runs[idx]
+		row := make([]Cell, 0, len(columns))
+
+		for _, column := range columns {
+			row = append(row, ctx.cell(ranked, column))
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// Text renders the leaderboard as an aligned plain-text table; see NewTable.
+func Text(lb *srapi.Leaderboard, columns ...Column) string {
+	return NewTable(lb, columns...).Text()
+}
+
+// Markdown renders the leaderboard as a Markdown table; see NewTable.
+func Markdown(lb *srapi.Leaderboard, columns ...Column) string {
+	return NewTable(lb, columns...).Markdown()
+}
+
+// HTML renders the leaderboard as an HTML table; see NewTable.
+func HTML(lb *srapi.Leaderboard, columns ...Column) string {
+	return NewTable(lb, columns...).HTML()
+}
+
+// Text renders the table with columns padded to the same width, separated by
+// two spaces, and a line of dashes below the headers. Name colors and links
+// are dropped.
+func (t *Table) Text() string {
+	widths := make([]int, len(t.Columns))
+
+	for idx, header := range t.Headers {
+		widths[idx] = utf8.RuneCountInString(header)
+	}
+
+	for _, row := range t.Rows {
+		for idx := range row {
+			if width := utf8.RuneCountInString(row[idx].Text()); width > widths[idx] {
+				widths[idx] = width
+			}
+		}
+	}
+
+	var lines []string
+
+	line := make([]string, len(t.Columns))
+	for idx, header := range t.Headers {
+		line[idx] = pad(header, widths[idx], t.alignRight(idx))
+	}
+
+	lines = append(lines, strings.TrimRight(strings.Join(line, "  "), " "))
+
+	for idx, width := range widths {
+		line[idx] = strings.Repeat("-", width)
+	}
+
+	lines = append(lines, strings.Join(line, "  "))
+
+	for _, row := range t.Rows {
+		for idx := range row {
+			line[idx] = pad(row[idx].Text(), widths[idx], row[idx].AlignRight)
+		}
+
+		lines = append(lines, strings.TrimRight(strings.Join(line, "  "), " "))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Markdown renders the table as a GitHub-flavoured Markdown table. Links are
+// kept, name colors are dropped.
+func (t *Table) Markdown() string {
+	var lines []string
+
+	line := make([]string, len(t.Columns))
+	for idx, header := range t.Headers {
+		line[idx] = escapeMarkdown(header)
+	}
+
+	lines = append(lines, "| "+strings.Join(line, " | ")+" |")
+
+	for idx := range t.Columns {
+		line[idx] = "---"
+		if t.alignRight(idx) {
+			line[idx] = "--:"
+		}
+	}
+
+	lines = append(lines, "| "+strings.Join(line, " | ")+" |")
+
+	for _, row := range t.Rows {
+		for idx := range row {
+			var spans []string
+
+			for _, span := range row[idx].Spans {
+				text := escapeMarkdown(span.Text)
+
+				if srapi.IsWebLink(span.Link) {
+					text = "[" + text + "](" + escapeMarkdownLink(span.Link) + ")"
+				}
+
+				spans = append(spans, text)
+			}
+
+			line[idx] = strings.Join(spans, ", ")
+		}
+
+		lines = append(lines, "| "+strings.Join(line, " | ")+" |")
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// HTML renders the table as a <table> element. Names are colored like on
+// speedrun.com: solid colors via the style attribute, gradients via a CSS
+// gradient clipped to the text. Cells and columns get classes, so that the
+// table can be styled further.
+func (t *Table) HTML() string {
+	var b strings.Builder
+
+	b.WriteString("<table class=\"leaderboard\">\n<thead>\n<tr>")
+
+	for idx, header := range t.Headers {
+		b.WriteString(`<th class="` + html.EscapeString(string(t.Columns[idx])) + `">`)
+		b.WriteString(html.EscapeString(header))
+		b.WriteString("</th>")
+	}
+
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	for _, row := range t.Rows {
+		b.WriteString("<tr>")
+
+		for idx := range row {
+			b.WriteString(`<td class="` + html.EscapeString(string(t.Columns[idx])) + `">`)
+
+			for spanIdx, span := range row[idx].Spans {
+				if spanIdx > 0 {
+					b.WriteString(", ")
+				}
+
+				b.WriteString(t.spanHTML(&span))
+			}
+
+			b.WriteString("</td>")
+		}
+
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("</tbody>\n</table>\n")
+
+	return b.String()
+}
+
+// spanHTML renders a single span, colored and linked if needed.
+func (t *Table) spanHTML(span *Span) string {
+	text := html.EscapeString(span.Text)
+
+	if style := t.style(span); len(style) > 0 {
+		text = `<span style="` + html.EscapeString(style) + `">` + text + `</span>`
+	}
+
+	if srapi.IsWebLink(span.Link) {
+		text = `<a href="` + html.EscapeString(span.Link) + `">` + text + `</a>`
+	}
+
+	return text
+}
+
+// style returns the inline CSS for a name.
+func (t *Table) style(span *Span) string {
+	if span.ColorFrom != nil && span.ColorTo != nil {
+		from, to := t.color(span.ColorFrom), t.color(span.ColorTo)
+
+		if len(from) > 0 && len(to) > 0 {
+			return "background: linear-gradient(to right, " + from + ", " + to + "); " +
+				"-webkit-background-clip: text; background-clip: text; color: transparent"
+		}
+	}
+
+	if span.Color != nil {
+		if color := t.color(span.Color); len(color) > 0 {
+			return "color: " + color
+		}
+	}
+
+	return ""
+}
+
+// color picks the light or dark variant. Anything but a hex color is
+// ignored, so nothing else can end up in the style attribute.
+func (t *Table) color(c *srapi.NameColor) string {
+	color := c.Light
+	if t.Dark {
+		color = c.Dark
+	}
+
+	if !hexColor.MatchString(color) {
+		return ""
+	}
+
+	return color
+}
+
+// hexColor matches CSS colors like "#E44141" or "#fff".
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}){1,2}$`)
+
+// alignRight checks if a column is right-aligned, which is decided by its
+// first row.
+func (t *Table) alignRight(column int) bool {
+	return len(t.Rows) > 0 && t.Rows[0][column].AlignRight
+}
+
+// context holds the embedded data of a leaderboard, indexed for lookups.
+type context struct {
+	lb        *srapi.Leaderboard
+	game      *srapi.Game
+	users     map[string]*srapi.User
+	guests    map[string]*srapi.Guest
+	platforms map[string]string
+	regions   map[string]string
+	variables map[string]*srapi.Variable
+}
+
+// newContext indexes the embedded data.
+func newContext(lb *srapi.Leaderboard) *context {
+	ctx := &context{
+		lb:        lb,
+		users:     make(map[string]*srapi.User),
+		guests:    make(map[string]*srapi.Guest),
+		platforms: make(map[string]string),
+		regions:   make(map[string]string),
+		variables: make(map[string]*srapi.Variable),
+	}
+
+	// the game is only used if it is embedded, as Game() would fetch it
+	if _, embedded := lb.GameData.(map[string]interface{}); embedded {
+		ctx.game, _ = lb.Game(srapi.NoEmbeds)
+	}
+
+	for _, player := range lb.Players().Players() {
+		if player.User != nil {
+			ctx.users[player.User.ID] = player.User
+		} else if player.Guest != nil {
+			ctx.guests[strings.ToLower(player.Guest.Name)] = player.Guest
+		}
+	}
+
+	lb.Platforms().Walk(func(p *srapi.Platform) bool {
+		ctx.platforms[p.ID] = p.Name
+		return true
+	})
+
+	lb.Regions().Walk(func(r *srapi.Region) bool {
+		ctx.regions[r.ID] = r.Name
+		return true
+	})
+
+	lb.Variables().Walk(func(v *srapi.Variable) bool {
+		ctx.variables[v.ID] = v
+		return true
+	})
+
+	return ctx
+}
+
+// cell renders a single cell.
+func (ctx *context) cell(ranked *srapi.RankedRun, column Column) Cell {
+	run := &ranked.Run
+
+	switch column {
+	case RankColumn:
+		return textCell(strconv.Itoa(ranked.Rank), true)
+
+	case PlayerColumn:
+		return Cell{Spans: ctx.players(run)}
+
+	case TimeColumn:
+		return textCell(ctx.formatTime(run.Times.Primary), true)
+
+	case RealtimeColumn, RealtimeWithoutLoadsColumn, IngameTimeColumn:
+		var duration *srapi.Duration
+
+		switch srapi.TimingMethod(column) {
+		case srapi.TimingRealtime:
+			duration = run.Times.Realtime
+		case srapi.TimingRealtimeWithoutLoads:
+			duration = run.Times.RealtimeWithoutLoads
+		default:
+			duration = run.Times.IngameTime
+		}
+
+		return textCell(ctx.formatTime(duration), true)
+
+	case DateColumn:
+		if run.Date == nil {
+			return textCell("", false)
+		}
+
+		return textCell(run.Date.Format("2006-01-02"), false)
+
+	case PlatformColumn:
+		return textCell(lookup(ctx.platforms, run.System.Platform), false)
+
+	case RegionColumn:
+		return textCell(lookup(ctx.regions, run.System.Region), false)
+
+	case EmulatedColumn:
+		if run.System.Emulated {
+			return textCell("Yes", false)
+		}
+
+		return textCell("No", false)
+
+	case VideoColumn:
+		if len(run.Videos.Links) == 0 {
+			return textCell("", false)
+		}
+
+		video := run.Videos.Links[0].URI
+
+		// video links are entered by users, so anything odd is not linked
+		if !srapi.IsWebLink(video) {
+			return textCell(video, false)
+		}
+
+		return Cell{Spans: []Span{{Text: "Video", Link: video}}}
+
+	case ValuesColumn:
+		return textCell(ctx.values(run), false)
+	}
+
+	return textCell("", false)
+}
+
+// players returns one span per runner.
+func (ctx *context) players(run *srapi.Run) []Span {
+	links, _ := run.PlayerLinks()
+
+	var spans []Span
+
+	for _, link := range links {
+		if len(link.ID) == 0 {
+			span := Span{Text: link.Name}
+
+			if guest, exists := ctx.guests[strings.ToLower(link.Name)]; exists {
+				span.Text = guest.Name
+			}
+
+			spans = append(spans, span)
+			continue
+		}
+
+		user, exists := ctx.users[link.ID]
+		if !exists {
+			spans = append(spans, Span{Text: link.ID})
+			continue
+		}
+
+		span := Span{
+			Text:  user.Names.International,
+			Link:  user.Weblink,
+			Color: user.NameStyle.Color,
+		}
+
+		if user.NameStyle.Style == "gradient" {
+			span.ColorFrom = user.NameStyle.ColorFrom
+			span.ColorTo = user.NameStyle.ColorTo
+		}
+
+		spans = append(spans, span)
+	}
+
+	return spans
+}
+
+// formatTime formats a time like the game does, if it is known.
+func (ctx *context) formatTime(duration *srapi.Duration) string {
+	if duration == nil {
+		return ""
+	}
+
+	if ctx.game != nil {
+		return ctx.game.FormatTime(duration, srapi.ClockStyle)
+	}
+
+	return duration.Format()
+}
+
+// values labels the run's values, leaving out the ones all runs in the
+// leaderboard share because the leaderboard was filtered by them.
+func (ctx *context) values(run *srapi.Run) string {
+	var labels []string
+
+	ids := make([]string, 0, len(run.Values))
+	for varID := range run.Values {
+		if _, filtered := ctx.lb.Values[varID]; !filtered {
+			ids = append(ids, varID)
+		}
+	}
+
+	sort.Strings(ids)
+
+	for _, varID := range ids {
+		label := run.Values[varID]
+
+		if variable, exists := ctx.variables[varID]; exists {
+			if choice, exists := variable.Values.Choices[label]; exists {
+				label = choice
+			}
+		}
+
+		labels = append(labels, label)
+	}
+
+	return strings.Join(labels, ", ")
+}
+
+// textCell creates a cell with a single unlinked span.
+func textCell(text string, alignRight bool) Cell {
+	return Cell{Spans: []Span{{Text: text}}, AlignRight: alignRight}
+}
+
+// lookup returns the name for an ID, or the ID itself if it is unknown.
+func lookup(names map[string]string, id string) string {
+	if name, exists := names[id]; exists {
+		return name
+	}
+
+	return id
+}
+
+// pad fills s with spaces up to the given width.
+func pad(s string, width int, alignRight bool) string {
+	padding := strings.Repeat(" ", width-utf8.RuneCountInString(s))
+
+	if alignRight {
+		return padding + s
+	}
+
+	return s + padding
+}
+
+// markdownEscaper escapes everything that could be mistaken for markup.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `|`, `\|`, `*`, `\*`, `_`, `\_`, "`", "\\`",
+	`[`, `\[`, `]`, `\]`, `<`, `&lt;`, `>`, `&gt;`, "\n", " ",
+)
+
+// escapeMarkdown escapes text for use in a table cell.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeMarkdownLink escapes a link target.
+func escapeMarkdownLink(s string) string {
+	return strings.NewReplacer(`(`, `%28`, `)`, `%29`, `|`, `%7C`, " ", "%20").Replace(s)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sgt-kabukiman/srapi"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testLeaderboard = `{
+	"weblink": "http://www.speedrun.com/smw#11_Exit",
+	"timing": "realtime",
+	"values": {"diff": "hard"},
+	"runs": [
+		{"place": 1, "run": {
+			"id": "run1",
+			"date": "2015-08-01",
+			"videos": {"links": [{"uri": "https://youtu.be/dQw4w9WgXcQ"}]},
+			"times": {"primary_t": 652.34, "realtime_t": 652.34, "ingame_t": 640},
+			"system": {"platform": "snes", "region": "pal", "emulated": false},
+			"values": {"diff": "hard", "ver": "jp"},
+			"players": [{"rel": "user", "id": "u1"}]
+		}},
+		{"place": 2, "run": {
+			"id": "run2",
+			"date": "2015-07-15",
+			"times": {"primary_t": 660, "realtime_t": 660},
+			"system": {"platform": "wii", "region": "", "emulated": true},
+			"values": {"diff": "hard", "ver": "us"},
+			"players": [{"rel": "user", "id": "u2"}, {"rel": "guest", "name": "bob"}]
+		}}
+	],
+	"game": {"data": {"id": "smw", "ruleset": {"show-milliseconds": true}}},
+	"players": {"data": [
+		{"rel": "user", "id": "u1", "names": {"international": "Alice"}, "weblink": "http://www.speedrun.com/user/Alice",
+		 "name-style": {"style": "solid", "color": {"light": "#E44141", "dark": "#EE4444"}}},
+		{"rel": "user", "id": "u2", "names": {"international": "Çharlie|C"}, "weblink": "http://www.speedrun.com/user/Charlie",
+		 "name-style": {"style": "gradient", "color-from": {"light": "#000", "dark": "#111"}, "color-to": {"light": "#fff", "dark": "#eee"}}},
+		{"rel": "guest", "name": "Bob"}
+	]},
+	"platforms": {"data": [{"id": "snes", "name": "SNES"}, {"id": "wii", "name": "Wii VC"}]},
+	"regions": {"data": [{"id": "pal", "name": "PAL"}]},
+	"variables": {"data": [
+		{"id": "ver", "name": "Version", "values": {"choices": {"jp": "Japanese", "us": "English"}}}
+	]}
+}`
+
+func testBoard() *srapi.Leaderboard {
+	lb := &srapi.Leaderboard{}
+	if err := json.Unmarshal([]byte(testLeaderboard), lb); err != nil {
+		panic(err)
+	}
+
+	return lb
+}
+
+func TestTables(t *testing.T) {
+	Convey("Preparing tables", t, func() {
+		table := NewTable(testBoard(), RankColumn, PlayerColumn, TimeColumn, IngameTimeColumn, PlatformColumn, RegionColumn, EmulatedColumn, ValuesColumn)
+		So(table.Headers, ShouldResemble, []string{"#", "Player", "Time", "IGT", "Platform", "Region", "Emulated", "Values"})
+		So(len(table.Rows), ShouldEqual, 2)
+
+		first := table.Rows[0]
+		So(first[0].Text(), ShouldEqual, "1")
+		So(first[1].Text(), ShouldEqual, "Alice")
+		So(first[2].Text(), ShouldEqual, "10:52.340")
+		So(first[3].Text(), ShouldEqual, "10:40.000")
+		So(first[4].Text(), ShouldEqual, "SNES")
+		So(first[5].Text(), ShouldEqual, "PAL")
+		So(first[6].Text(), ShouldEqual, "No")
+		So(first[7].Text(), ShouldEqual, "Japanese")
+
+		second := table.Rows[1]
+		So(second[1].Text(), ShouldEqual, "Çharlie|C, Bob")
+		So(second[3].Text(), ShouldEqual, "")
+		So(second[6].Text(), ShouldEqual, "Yes")
+		So(second[1].Spans[0].ColorFrom.Light, ShouldEqual, "#000")
+		So(second[1].Spans[1].Link, ShouldEqual, "")
+	})
+
+	Convey("Rendering plain text", t, func() {
+		text := Text(testBoard(), RankColumn, PlayerColumn, TimeColumn, PlatformColumn)
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+		So(lines, ShouldResemble, []string{
+			"#  Player               Time  Platform",
+			"-  --------------  ---------  --------",
+			"1  Alice           10:52.340  SNES",
+			"2  Çharlie|C, Bob  11:00.000  Wii VC",
+		})
+	})
+
+	Convey("Rendering Markdown", t, func() {
+		md := Markdown(testBoard(), RankColumn, PlayerColumn, VideoColumn)
+		lines := strings.Split(strings.TrimRight(md, "\n"), "\n")
+
+		So(lines, ShouldResemble, []string{
+			"| # | Player | Video |",
+			"| --: | --- | --- |",
+			"| 1 | [Alice](http://www.speedrun.com/user/Alice) | [Video](https://youtu.be/dQw4w9WgXcQ) |",
+			"| 2 | [Çharlie\\|C](http://www.speedrun.com/user/Charlie), Bob |  |",
+		})
+	})
+
+	Convey("Rendering HTML", t, func() {
+		table := NewTable(testBoard(), PlayerColumn)
+
+		out := table.HTML()
+		So(out, ShouldStartWith, `<table class="leaderboard">`)
+		So(out, ShouldContainSubstring, `<th class="player">Player</th>`)
+		So(out, ShouldContainSubstring, `<a href="http://www.speedrun.com/user/Alice"><span style="color: #E44141">Alice</span></a>`)
+		So(out, ShouldContainSubstring, `linear-gradient(to right, #000, #fff)`)
+		So(out, ShouldContainSubstring, `, Bob</td>`)
+
+		table.Dark = true
+		So(table.HTML(), ShouldContainSubstring, `color: #EE4444`)
+	})
+
+	Convey("Links and colors from users are sanitized", t, func() {
+		lb := testBoard()
+		lb.Runs[0].Run.Videos.Links[0].URI = "javascript:alert(1)"
+
+		table := NewTable(lb, PlayerColumn, VideoColumn)
+		table.Rows[0][0].Spans[0].Color.Light = "red; background: url(x)"
+
+		out := table.HTML()
+		So(out, ShouldContainSubstring, `<td class="video">javascript:alert(1)</td>`)
+		So(out, ShouldNotContainSubstring, "url(x)")
+		So(out, ShouldContainSubstring, `<a href="http://www.speedrun.com/user/Alice">Alice</a>`)
+
+		table.Rows[0][0].Spans[0].Link = "javascript:alert(2)"
+		So(table.HTML(), ShouldNotContainSubstring, "alert(2)\"")
+		So(table.Markdown(), ShouldNotContainSubstring, "](javascript")
+	})
+
+	Convey("Missing embeds fall back to IDs", t, func() {
+		lb := testBoard()
+		lb.PlayersData = nil
+		lb.PlatformsData = nil
+		lb.GameData = "smw"
+
+		row := NewTable(lb, PlayerColumn, TimeColumn, PlatformColumn).Rows[1]
+		So(row[0].Text(), ShouldEqual, "u2, bob")
+		So(row[1].Text(), ShouldEqual, "11:00")
+		So(row[2].Text(), ShouldEqual, "wii")
+	})
+}
@@ -119,6 +119,24 @@ func RunByID(id string, embeds string) (*Run, *Error) {
 	return fetchRun(request{"GET", "/runs/" + id, nil, nil, nil, embeds})
 }
 
+// GameID returns the ID of the run's game, regardless of whether the game has
+// been embedded or not. No request is performed.
+func (r *Run) GameID() string {
+	return recastToID(r.GameData)
+}
+
+// CategoryID returns the ID of the run's category, regardless of whether the
+// category has been embedded or not. No request is performed.
+func (r *Run) CategoryID() string {
+	return recastToID(r.CategoryData)
+}
+
+// LevelID returns the ID of the run's level, or an empty string for full-game
+// runs. No request is performed.
+func (r *Run) LevelID() string {
+	return recastToID(r.LevelData)
+}
+
 // Game extracts the embedded game, if possible, otherwise it will fetch the
 // game by doing one additional request. If nothing on the server side is fubar,
 // then this function should never return nil.
@@ -256,6 +274,35 @@ func (r *Run) Examiner() (*User, *Error) {
 	return fetchUserLink(firstLink(r, "examiner"))
 }
 
+// timeFor returns the time of the run measured with the given timing method.
+// It returns nil if that time is not available.
+func (r *Run) timeFor(method TimingMethod) *Duration {
+	switch method {
+	case TimingRealtime:
+		return r.Times.Realtime
+
+	case TimingRealtimeWithoutLoads:
+		return r.Times.RealtimeWithoutLoads
+
+	case TimingIngameTime:
+		return r.Times.IngameTime
+	}
+
+	return nil
+}
+
+// matchesValues checks if the run has all the given variable values (mapping
+// of variable ID to value ID).
+func (r *Run) matchesValues(values map[string]string) bool {
+	for varID, valueID := range values {
+		if r.Values[varID] != valueID {
+			return false
+		}
+	}
+
+	return true
+}
+
 // for the 'hasLinks' interface
 func (r *Run) links() []Link {
 	return r.Links
@@ -90,6 +90,36 @@ func TestRuns(t *testing.T) {
 		})
 	})
 
+	Convey("Resuming an interrupted iteration", t, func() {
+		runs, err := Runs(&RunFilter{Status: "verified"}, &Sorting{"submitted", Ascending}, &Cursor{0, 3}, NoEmbeds)
+		So(err, ShouldBeNil)
+
+		var seen []string
+
+		// read a bit more than one page, then stop
+		it := runs.Iterator()
+		for run := range it.Output() {
+			seen = append(seen, run.ID)
+
+			if len(seen) == 4 {
+				it.Stop()
+			}
+		}
+
+		pos := it.Position()
+		So(pos.URI, ShouldNotBeBlank)
+
+		resumed, err := ResumeRuns(pos)
+		So(err, ShouldBeNil)
+		So(resumed.Pagination.Offset, ShouldEqual, 4)
+		So(resumed.Pagination.Max, ShouldEqual, 3)
+
+		// the next run must be the one following the last one we have seen
+		all, err := Runs(&RunFilter{Status: "verified"}, &Sorting{"submitted", Ascending}, &Cursor{0, 5}, NoEmbeds)
+		So(err, ShouldBeNil)
+		So(resumed.First().ID, ShouldEqual, all.Data[4].ID)
+	})
+
 	Convey("Fetching related resources", t, func() {
 		Convey("Game", func() {
 			Convey("Without embedding", func() {
@@ -250,3 +280,56 @@ func TestRuns(t *testing.T) {
 		})
 	})
 }
+
+func TestRunQueries(t *testing.T) {
+	runs := &RunCollection{}
+
+	for _, id := range []string{"a", "b", "c", "b", "d", "e", "a"} {
+		run := Run{ID: id}
+		run.System.Platform = "p-" + id
+
+		runs.Data = append(runs.Data, run)
+	}
+
+	ids := func(list []*Run) []string {
+		var result []string
+
+		for _, run := range list {
+			result = append(result, run.ID)
+		}
+
+		return result
+	}
+
+	Convey("Filtering runs", t, func() {
+		result := runs.Filter(func(r *Run) bool { return r.ID != "b" }).Collect()
+		So(ids(result), ShouldResemble, []string{"a", "c", "d", "e", "a"})
+	})
+
+	Convey("Skipping, taking and de-duplicating runs", t, func() {
+		So(ids(runs.Query().Skip(2).Take(3).Collect()), ShouldResemble, []string{"c", "b", "d"})
+		So(ids(runs.Query().Distinct().Collect()), ShouldResemble, []string{"a", "b", "c", "d", "e"})
+		So(ids(runs.Query().Distinct().Skip(1).Take(2).Collect()), ShouldResemble, []string{"b", "c"})
+		So(runs.Query().Take(0).Collect(), ShouldBeEmpty)
+	})
+
+	Convey("Sorting runs", t, func() {
+		desc := runs.SortBy(func(a, b *Run) bool { return a.ID > b.ID }).Take(4)
+		So(ids(desc.Collect()), ShouldResemble, []string{"e", "d", "c", "b"})
+	})
+
+	Convey("Grouping and mapping runs", t, func() {
+		groups := runs.GroupBy(func(r *Run) string { return r.ID })
+		So(groups, ShouldHaveLength, 5)
+		So(groups["a"], ShouldHaveLength, 2)
+		So(groups["e"], ShouldHaveLength, 1)
+
+		platforms := runs.Query().Distinct().Map(func(r *Run) interface{} { return r.System.Platform })
+		So(platforms, ShouldResemble, []interface{}{"p-a", "p-b", "p-c", "p-d", "p-e"})
+	})
+
+	Convey("Queries can be evaluated multiple times", t, func() {
+		query := runs.Query().Distinct().Take(2)
+		So(ids(query.Collect()), ShouldResemble, ids(query.Collect()))
+	})
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+// Package splits fetches and decodes the splits that are linked from runs on
+// speedrun.com. As of now, all those splits are hosted on splits.io.
+package splits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+)
+
+// BaseURL is the base URL of splits.io. Change it to point the package at
+// a different server (e.g. a local stand-in for testing).
+var BaseURL = "https://splits.io"
+
+// our http client
+var httpClient = &http.Client{}
+
+// Splits are the splits of a run.
+type Splits struct {
+	// splits.io ID
+	ID string
+
+	// the run the splits belong to
+	Run *srapi.Run
+
+	// the timing method that the segment times are measured with, either
+	// TimingRealtime or TimingIngameTime
+	Timing srapi.TimingMethod
+
+	// the game and category names, as entered in the splits file
+	Game     string
+	Category string
+
+	// number of attempts recorded in the splits file
+	Attempts int
+
+	// the overall time
+	Time srapi.Duration
+
+	// the sum of all best segments
+	SumOfBest srapi.Duration
+
+	// the segments, in order
+	Segments []Segment
+}
+
+// Segment is a single segment of the splits.
+type Segment struct {
+	// position of the segment, starting at 0
+	Number int
+
+	// segment name
+	Name string
+
+	// time spent in this segment
+	Time srapi.Duration
+
+	// time at the end of this segment (the split time)
+	End srapi.Duration
+
+	// best time ever achieved in this segment
+	Best srapi.Duration
+
+	// true if Time equals Best, i.e. the run contains a gold split
+	Gold bool
+
+	// true if the runner did not split at the end of the segment; its time is
+	// then part of the next segment
+	Skipped bool
+}
+
+// Fetch retrieves the splits of a run. If method is empty, the timing method
+// is derived from the run's primary time (in-game time if that is the primary
+// time, realtime otherwise). An error is returned if the run has no splits.
+func Fetch(run *srapi.Run, method srapi.TimingMethod) (*Splits, *srapi.Error) {
+	if run.Splits == nil || len(run.Splits.URI) == 0 {
+		return nil, &srapi.Error{Status: srapi.ErrorNoSuchLink, Message: "The run has no splits."}
+	}
+
+	link, err := url.Parse(run.Splits.URI)
+	if err != nil {
+		return nil, &srapi.Error{URL: run.Splits.URI, Status: srapi.ErrorBadURL, Message: err.Error()}
+	}
+
+	id := strings.Trim(link.Path, "/")
+	if len(id) == 0 || strings.Contains(id, "/") {
+		return nil, &srapi.Error{URL: run.Splits.URI, Status: srapi.ErrorBadURL, Message: "Could not find the splits ID in the link."}
+	}
+
+	if len(method) == 0 {
+		method = primaryMethod(run)
+	}
+
+	if method != srapi.TimingRealtime && method != srapi.TimingIngameTime {
+		return nil, &srapi.Error{Status: srapi.ErrorBadLogic, Message: "Splits are only available as realtime or in-game time."}
+	}
+
+	doc, failure := fetchDocument(id)
+	if failure != nil {
+		return nil, failure
+	}
+
+	return doc.splits(run, method), nil
+}
+
+// primaryMethod determines the timing method of the run's primary time.
+func primaryMethod(run *srapi.Run) srapi.TimingMethod {
+	primary := run.Times.Primary
+	ingame := run.Times.IngameTime
+
+	if primary != nil && ingame != nil && primary.Equal(*ingame) {
+		return srapi.TimingIngameTime
+	}
+
+	return srapi.TimingRealtime
+}
+
+// document is the response of the splits.io API
+type document struct {
+	Run struct {
+		ID       string
+		Attempts int
+		Game     struct {
+			Name string
+		}
+		Category struct {
+			Name string
+		}
+
+		RealtimeDuration  int64 `json:"realtime_duration_ms"`
+		RealtimeSumOfBest int64 `json:"realtime_sum_of_best_ms"`
+		GametimeDuration  int64 `json:"gametime_duration_ms"`
+		GametimeSumOfBest int64 `json:"gametime_sum_of_best_ms"`
+
+		Segments []struct {
+			Name          string
+			SegmentNumber int `json:"segment_number"`
+
+			RealtimeDuration int64 `json:"realtime_duration_ms"`
+			RealtimeEnd      int64 `json:"realtime_end_ms"`
+			RealtimeShortest int64 `json:"realtime_shortest_duration_ms"`
+			RealtimeGold     bool  `json:"realtime_gold"`
+			RealtimeSkipped  bool  `json:"realtime_skipped"`
+
+			GametimeDuration int64 `json:"gametime_duration_ms"`
+			GametimeEnd      int64 `json:"gametime_end_ms"`
+			GametimeShortest int64 `json:"gametime_shortest_duration_ms"`
+			GametimeGold     bool  `json:"gametime_gold"`
+			GametimeSkipped  bool  `json:"gametime_skipped"`
+		}
+	}
+}
+
+// fetchDocument requests the splits from the API.
+func fetchDocument(id string) (*document, *srapi.Error) {
+	uri := BaseURL + "/api/v4/runs/" + url.PathEscape(id)
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, &srapi.Error{Method: "GET", URL: uri, Status: srapi.ErrorBadURL, Message: err.Error()}
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "go-srapi/"+srapi.Version)
+
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return nil, &srapi.Error{Method: "GET", URL: uri, Status: srapi.ErrorNetwork, Message: err.Error()}
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return nil, &srapi.Error{Method: "GET", URL: uri, Status: response.StatusCode, Message: "Could not fetch the splits."}
+	}
+
+	doc := &document{}
+
+	if err := json.NewDecoder(response.Body).Decode(doc); err != nil {
+		return nil, &srapi.Error{Method: "GET", URL: uri, Status: srapi.ErrorBadJSON, Message: err.Error()}
+	}
+
+	return doc, nil
+}
+
+// splits converts the API response, picking the times for the timing method.
+func (d *document) splits(run *srapi.Run, method srapi.TimingMethod) *Splits {
+	result := &Splits{
+		ID:        d.Run.ID,
+		Run:       run,
+		Timing:    method,
+		Game:      d.Run.Game.Name,
+		Category:  d.Run.Category.Name,
+		Attempts:  d.Run.Attempts,
+		Time:      millis(d.Run.RealtimeDuration),
+		SumOfBest: millis(d.Run.RealtimeSumOfBest),
+	}
+
+	if method == srapi.TimingIngameTime {
+		result.Time = millis(d.Run.GametimeDuration)
+		result.SumOfBest = millis(d.Run.GametimeSumOfBest)
+	}
+
+	for _, seg := range d.Run.Segments {
+		segment := Segment{
+			Number:  seg.SegmentNumber,
+			Name:    seg.Name,
+			Time:    millis(seg.RealtimeDuration),
+			End:     millis(seg.RealtimeEnd),
+			Best:    millis(seg.RealtimeShortest),
+			Gold:    seg.RealtimeGold,
+			Skipped: seg.RealtimeSkipped,
+		}
+
+		if method == srapi.TimingIngameTime {
+			segment.Time = millis(seg.GametimeDuration)
+			segment.End = millis(seg.GametimeEnd)
+			segment.Best = millis(seg.GametimeShortest)
+			segment.Gold = seg.GametimeGold
+			segment.Skipped = seg.GametimeSkipped
+		}
+
+		result.Segments = append(result.Segments, segment)
+	}
+
+	return result
+}
+
+// Golds returns the segments with gold splits.
+func (s *Splits) Golds() []Segment {
+	var result []Segment
+
+	for _, segment := range s.Segments {
+		if segment.Gold {
+			result = append(result, segment)
+		}
+	}
+
+	return result
+}
+
+// PossibleTimeSave returns how much time could have been saved by matching
+// the best time in every segment.
+func (s *Splits) PossibleTimeSave() srapi.Duration {
+	return srapi.Duration{Duration: s.Time.Duration - s.SumOfBest.Duration}
+}
+
+// millis turns milliseconds into a Duration.
+func millis(ms int64) srapi.Duration {
+	return srapi.Duration{Duration: time.Duration(ms) * time.Millisecond}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package splits
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testDocument = `{"run": {
+	"id": "abc1",
+	"attempts": 120,
+	"game": {"name": "Super Mario World"},
+	"category": {"name": "11 Exit"},
+	"realtime_duration_ms": 600000,
+	"realtime_sum_of_best_ms": 590000,
+	"gametime_duration_ms": 580000,
+	"gametime_sum_of_best_ms": 575000,
+	"segments": [
+		{"name": "Yoshi's Island", "segment_number": 0,
+		 "realtime_duration_ms": 100000, "realtime_end_ms": 100000, "realtime_shortest_duration_ms": 100000, "realtime_gold": true,
+		 "gametime_duration_ms": 98000, "gametime_end_ms": 98000, "gametime_shortest_duration_ms": 97000},
+		{"name": "Bowser", "segment_number": 1,
+		 "realtime_duration_ms": 500000, "realtime_end_ms": 600000, "realtime_shortest_duration_ms": 490000,
+		 "gametime_duration_ms": 482000, "gametime_end_ms": 580000, "gametime_shortest_duration_ms": 478000, "gametime_gold": false}
+	]
+}}`
+
+func TestSplits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/runs/abc1" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(testDocument))
+	}))
+	defer server.Close()
+
+	previous := BaseURL
+	BaseURL = server.URL
+	defer func() { BaseURL = previous }()
+
+	newRun := func(splits string) *srapi.Run {
+		run := &srapi.Run{ID: "run1"}
+		run.Times.Primary = &srapi.Duration{Duration: 10 * time.Minute}
+		run.Times.Realtime = &srapi.Duration{Duration: 10 * time.Minute}
+
+		if len(splits) > 0 {
+			run.Splits = &srapi.Link{Relation: "splits", URI: splits}
+		}
+
+		return run
+	}
+
+	Convey("Fetching realtime splits", t, func() {
+		run := newRun("https://splits.io/abc1")
+
+		splits, err := Fetch(run, "")
+		So(err, ShouldBeNil)
+		So(splits.Run, ShouldEqual, run)
+		So(splits.Timing, ShouldEqual, srapi.TimingRealtime)
+		So(splits.ID, ShouldEqual, "abc1")
+		So(splits.Game, ShouldEqual, "Super Mario World")
+		So(splits.Attempts, ShouldEqual, 120)
+		So(splits.Time.Duration, ShouldEqual, 10*time.Minute)
+		So(splits.PossibleTimeSave().Duration, ShouldEqual, 10*time.Second)
+		So(len(splits.Segments), ShouldEqual, 2)
+		So(splits.Segments[1].Name, ShouldEqual, "Bowser")
+		So(splits.Segments[1].End.Duration, ShouldEqual, 10*time.Minute)
+		So(splits.Segments[1].Best.Duration, ShouldEqual, 490*time.Second)
+
+		golds := splits.Golds()
+		So(len(golds), ShouldEqual, 1)
+		So(golds[0].Number, ShouldEqual, 0)
+	})
+
+	Convey("Fetching in-game time splits", t, func() {
+		run := newRun("https://splits.io/abc1")
+		run.Times.Primary = &srapi.Duration{Duration: 580 * time.Second}
+		run.Times.IngameTime = &srapi.Duration{Duration: 580 * time.Second}
+
+		splits, err := Fetch(run, "")
+		So(err, ShouldBeNil)
+		So(splits.Timing, ShouldEqual, srapi.TimingIngameTime)
+		So(splits.Time.Duration, ShouldEqual, 580*time.Second)
+		So(splits.Segments[0].Time.Duration, ShouldEqual, 98*time.Second)
+		So(len(splits.Golds()), ShouldEqual, 0)
+	})
+
+	Convey("Fetching invalid splits", t, func() {
+		_, err := Fetch(newRun(""), "")
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, srapi.ErrorNoSuchLink)
+
+		_, err = Fetch(newRun("https://splits.io/abc1"), srapi.TimingRealtimeWithoutLoads)
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, srapi.ErrorBadLogic)
+
+		_, err = Fetch(newRun("https://splits.io/missing"), "")
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, 404)
+	})
+}
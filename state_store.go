@@ -0,0 +1,134 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateStore persists arbitrary values by key, e.g. to let a Watcher remember
+// what it has already seen across restarts. Implementations must be safe for
+// concurrent use.
+type StateStore interface {
+	// Load returns the value stored for the key, or nil if there is none.
+	Load(key string) ([]byte, error)
+
+	// Save stores the value for the key, replacing any previous value.
+	Save(key string, value []byte) error
+}
+
+// MemoryStore is a StateStore that keeps everything in memory.
+type MemoryStore struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Load implements the StateStore interface.
+func (s *MemoryStore) Load(key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.values[key], nil
+}
+
+// Save implements the StateStore interface.
+func (s *MemoryStore) Save(key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// FileStore is a StateStore that keeps all values in a single JSON file. The
+// file is rewritten on every Save, so it is meant for small amounts of state.
+type FileStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileStore creates a store backed by the given file. The file does not
+// need to exist yet.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements the StateStore interface.
+func (s *FileStore) Load(key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return values[key], nil
+}
+
+// Save implements the StateStore interface. The file is replaced atomically.
+func (s *FileStore) Save(key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	values[key] = value
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// read decodes the whole file; a missing file is an empty store.
+func (s *FileStore) read() (map[string][]byte, error) {
+	values := make(map[string][]byte)
+
+	encoded, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
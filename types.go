@@ -4,10 +4,11 @@ package srapi
 
 import (
 	"errors"
-	"fmt"
+	"math/big"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,6 +27,20 @@ type Link struct {
 	URI      string `json:"uri"`
 }
 
+// IsWebLink checks if a URI is an absolute http or https URL. Links that are
+// entered by users (like videos) should be checked before putting them into
+// HTML, so that e.g. "javascript:" links cannot sneak in.
+func IsWebLink(uri string) bool {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || len(u.Host) == 0 {
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+
+	return scheme == "http" || scheme == "https"
+}
+
 // checks if the link exists
 func (l *Link) exists() bool {
 	return l != nil
@@ -46,6 +61,9 @@ type AssetLink struct {
 	Height int
 }
 
+// maxPageSize is the maximum number of items the API returns per page.
+const maxPageSize = 200
+
 // Pagination contains information on how to navigate through multiple pages
 // of results.
 type Pagination struct {
@@ -60,6 +78,109 @@ func (p *Pagination) links() []Link {
 	return p.Links
 }
 
+// sourced describes a response that wants to know the URL it was fetched from.
+type sourced interface {
+	setSource(string)
+}
+
+// Position is a serializable position inside a paginated collection. It is
+// the API URL of the underlying query (including filter, sorting, embeds and
+// the page size) with its offset pointing to the next item that has not been
+// consumed yet. Positions can be persisted, e.g. as JSON, and be used to
+// resume an iteration later on, even in another process.
+type Position struct {
+	URI string `json:"uri"`
+}
+
+// newPosition creates a position by taking the URL a page was fetched from and
+// replacing its offset. An empty source results in the zero Position.
+func newPosition(source string, offset int) Position {
+	if len(source) == 0 {
+		return Position{}
+	}
+
+	return Position{moveURL(source, &Cursor{offset, 0})}
+}
+
+// moveURL replaces the offset of a collection URL (and its max value, if the
+// cursor has one) with the values from a cursor. An empty string is returned
+// if source is not a valid URL.
+func moveURL(source string, cursor *Cursor) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return ""
+	}
+
+	values := u.Query()
+	values.Del("offset")
+	u.RawQuery = values.Encode()
+
+	cursor.applyToURL(u)
+
+	return u.String()
+}
+
+// pageSource returns the URL that further pages of a collection can be fetched
+// from. source is the URL the collection has been fetched from; if it's
+// unknown, the link to the next page is used instead. An empty string is
+// returned if there are no further pages.
+func pageSource(source string, p *Pagination) string {
+	next := firstLink(p, "next")
+	if next == nil {
+		return ""
+	}
+
+	if len(source) > 0 {
+		return source
+	}
+
+	return next.URI
+}
+
+// pageRequest creates a request for the page at the given cursor of the
+// collection that has been fetched from source.
+func pageRequest(source string, cursor *Cursor) request {
+	pos := Position{moveURL(source, cursor)}
+
+	return pos.request(nil, nil, NoEmbeds)
+}
+
+// checks if the position can be used to fetch something
+func (p *Position) exists() bool {
+	return p != nil && strings.HasPrefix(p.URI, BaseURL)
+}
+
+// request turns a position into a GET request.
+func (p *Position) request(filter filter, sort *Sorting, embeds string) request {
+	return (&Link{"position", p.URI}).request(filter, sort, embeds)
+}
+
+// iteratorState is shared between an iterator's worker goroutine and the
+// iterator's users and keeps track of how far the iteration has come.
+type iteratorState struct {
+	mutex  sync.Mutex
+	source string
+	offset int
+}
+
+// advance records that all items before offset on the page fetched from
+// source have been consumed.
+func (s *iteratorState) advance(source string, offset int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.source = source
+	s.offset = offset
+}
+
+// position returns the current state as a Position.
+func (s *iteratorState) position() Position {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return newPosition(s.source, s.offset)
+}
+
 // filter describes anything that can apply itself to a URL.
 type filter interface {
 	applyToURL(*url.URL)
@@ -228,59 +349,114 @@ func (d *Date) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// ErrParseDuration is an error that occurs when a JSON value is not a valid float value
-var ErrParseDuration = errors.New(`ErrParseDuration: value should be a valid float`)
+// ErrParseDuration is an error that occurs when a JSON value is not a valid number
+var ErrParseDuration = errors.New(`ErrParseDuration: value should be a valid number`)
 
-// Duration is a custom time.Time wrapper that allows dates without times in JSON
-// documents.
+// Duration is a custom time.Duration wrapper that can be decoded from and
+// encoded to the number of seconds in JSON documents, without losing precision.
 type Duration struct {
 	time.Duration
 }
 
-// MarshalJSON implements the json.Marshaler interface
+// MarshalJSON implements the json.Marshaler interface. The number of seconds
+// is written with as many decimals as needed to represent the duration
+// exactly, so decoding it again yields the same Duration.
 func (d Duration) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("%.3f", d.Seconds())), nil
+	value := d.Duration
+	sign := ""
+
+	if value < 0 {
+		value = -value
+		sign = "-"
+	}
+
+	result := sign + strconv.FormatInt(int64(value/time.Second), 10)
+
+	if fraction := int64(value % time.Second); fraction > 0 {
+		digits := strconv.FormatInt(fraction, 10)
+		digits = strings.Repeat("0", 9-len(digits)) + digits
+
+		result += "." + strings.TrimRight(digits, "0")
+	}
+
+	return []byte(result), nil
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface
+// UnmarshalJSON implements the json.Unmarshaler interface. The number is
+// decoded exactly (rounded to the nearest nanosecond), so even times of many
+// hours keep their milliseconds.
 func (d *Duration) UnmarshalJSON(b []byte) error {
-	parsed, err := strconv.ParseFloat(string(b), 32)
+	if string(b) == "null" {
+		return nil
+	}
 
-	if err != nil {
+	// big.Rat would also accept fractions like "1/3"
+	if strings.Contains(string(b), "/") {
 		return ErrParseDuration
 	}
 
-	d.Duration = time.Duration(parsed * float64(time.Second))
-
-	return nil
-}
+	seconds, okay := new(big.Rat).SetString(string(b))
+	if !okay {
+		return ErrParseDuration
+	}
 
-// Format returns a human readable time in the form of "[[HH:]MM:]SS[.MS]".
-func (d *Duration) Format() string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	seconds := int(d.Seconds()) % 60
-	milli := (d.Seconds() - float64(int(d.Seconds())))
+	nanos := new(big.Rat).Mul(seconds, big.NewRat(int64(time.Second), 1))
 
-	var list []string
+	// round half away from zero
+	num, denom := nanos.Num(), nanos.Denom()
+	rounded, remainder := new(big.Int).QuoRem(num, denom, new(big.Int))
 
-	if hours > 0 {
-		list = append(list, fmt.Sprintf("%02d", hours))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(denom) >= 0 {
+		rounded.Add(rounded, big.NewInt(int64(num.Sign())))
 	}
 
-	if len(list) > 0 || minutes > 0 {
-		list = append(list, fmt.Sprintf("%02d", minutes))
+	if !rounded.IsInt64() {
+		return ErrParseDuration
 	}
 
-	if len(list) > 0 || seconds > 0 {
-		list = append(list, fmt.Sprintf("%02d", seconds))
+	d.Duration = time.Duration(rounded.Int64())
+
+	return nil
+}
+
+// Compare returns -1 if d is shorter than other, 1 if it is longer and 0 if
+// both are equal.
+func (d Duration) Compare(other Duration) int {
+	switch {
+	case d.Duration < other.Duration:
+		return -1
+
+	case d.Duration > other.Duration:
+		return 1
 	}
 
-	formatted := strings.TrimPrefix(strings.Join(list, ":"), "0")
+	return 0
+}
+
+// Equal checks if both durations are exactly the same.
+func (d Duration) Equal(other Duration) bool {
+	return d.Duration == other.Duration
+}
+
+// Less checks if d is shorter than other.
+func (d Duration) Less(other Duration) bool {
+	return d.Duration < other.Duration
+}
+
+// CompareTimes compares two optional times, like Compare(). A missing time
+// (nil) is considered to be longer than any existing time, so sorting by it
+// puts runs without that time last.
+func CompareTimes(a *Duration, b *Duration) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+
+	case a == nil:
+		return 1
 
-	if milli >= 0.001 {
-		formatted += fmt.Sprintf(".%02d", int(milli*1000+0.5)) // +0.5 for easy rounding
+	case b == nil:
+		return -1
 	}
 
-	return formatted
+	return a.Compare(*b)
 }
@@ -0,0 +1,82 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPositions(t *testing.T) {
+	Convey("Positions are built from the source URL of a page", t, func() {
+		source := BaseURL + "/runs?game=om1m3625&max=20&offset=40&orderby=submitted"
+
+		pos := newPosition(source, 47)
+		So(pos.exists(), ShouldBeTrue)
+
+		u, err := url.Parse(pos.URI)
+		So(err, ShouldBeNil)
+		So(u.Query().Get("offset"), ShouldEqual, "47")
+		So(u.Query().Get("max"), ShouldEqual, "20")
+		So(u.Query().Get("game"), ShouldEqual, "om1m3625")
+		So(u.Query().Get("orderby"), ShouldEqual, "submitted")
+
+		pos = newPosition(source, 0)
+		u, _ = url.Parse(pos.URI)
+		So(u.Query().Get("offset"), ShouldBeBlank)
+	})
+
+	Convey("Collections without a source yield an empty position", t, func() {
+		pos := newPosition("", 12)
+		So(pos.URI, ShouldBeBlank)
+		So(pos.exists(), ShouldBeFalse)
+
+		_, err := ResumeRuns(pos)
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, ErrorBadLogic)
+	})
+
+	Convey("Positions survive a JSON roundtrip", t, func() {
+		pos := newPosition(BaseURL+"/games?max=5", 10)
+
+		encoded, err := json.Marshal(pos)
+		So(err, ShouldBeNil)
+
+		decoded := Position{}
+		So(json.Unmarshal(encoded, &decoded), ShouldBeNil)
+		So(decoded, ShouldResemble, pos)
+	})
+
+	Convey("Pages can be addressed directly", t, func() {
+		source := BaseURL + "/runs?game=om1m3625&max=20&offset=40"
+		p := Pagination{Offset: 40, Max: 20, Size: 20}
+
+		So(pageSource(source, &p), ShouldBeBlank)
+
+		p.Links = []Link{{"next", BaseURL + "/runs?game=om1m3625&max=20&offset=60"}}
+		So(pageSource(source, &p), ShouldEqual, source)
+		So(pageSource("", &p), ShouldEqual, p.Links[0].URI)
+
+		u, _ := url.Parse(moveURL(source, &Cursor{123, 1}))
+		So(u.Query().Get("offset"), ShouldEqual, "123")
+		So(u.Query().Get("max"), ShouldEqual, "1")
+		So(u.Query().Get("game"), ShouldEqual, "om1m3625")
+
+		req := pageRequest(source, &Cursor{0, 5})
+		So(req.url, ShouldEqual, "/runs?game=om1m3625&max=5")
+	})
+}
+
+func TestWebLinks(t *testing.T) {
+	Convey("Only absolute http(s) URLs are web links", t, func() {
+		So(IsWebLink("https://youtu.be/dQw4w9WgXcQ"), ShouldBeTrue)
+		So(IsWebLink(" HTTP://www.speedrun.com/smw "), ShouldBeTrue)
+		So(IsWebLink("javascript:alert(1)"), ShouldBeFalse)
+		So(IsWebLink("//evil.example/x"), ShouldBeFalse)
+		So(IsWebLink("data:text/html,<script>"), ShouldBeFalse)
+		So(IsWebLink("youtu.be/dQw4w9WgXcQ"), ShouldBeFalse)
+	})
+}
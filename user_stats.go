@@ -0,0 +1,164 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import "time"
+
+// UserStats is an aggregation of a user's personal bests.
+type UserStats struct {
+	// the user the stats are about
+	UserID string
+
+	// number of PBs
+	PersonalBests int
+
+	// number of PBs that are ranked first
+	WorldRecords int
+
+	// number of PBs that are ranked first, second or third
+	Podiums int
+
+	// number of distinct games the user has PBs in
+	Games int
+
+	// number of distinct categories the user has PBs in
+	Categories int
+
+	// number of full-game PBs
+	FullGame int
+
+	// number of individual-level PBs
+	IndividualLevel int
+
+	// the sum of the primary times of all PBs
+	TotalTime Duration
+
+	// the PB that was done last, nil if there are no PBs
+	MostRecent *PersonalBest
+
+	// The average rank percentile over all PBs, between 0 and 100. The
+	// percentile of a PB is the share of runs on its leaderboard that are
+	// not faster, so a world record always has 100.
+	AveragePercentile float64
+}
+
+// UserStatistics computes the stats for a user by fetching their personal
+// bests and the leaderboard of each of them (the one for the PB's sub-category
+// values, if the game has sub-categories). This takes one request for the
+// PBs, one per distinct game for its variables and one per distinct
+// leaderboard.
+func UserStatistics(userID string) (*UserStats, *Error) {
+	pbs, err := fetchPersonalBests(request{"GET", "/users/" + userID + "/personal-bests", nil, nil, nil, NoEmbeds})
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string][]*Variable)
+	sizes := make(map[string]int)
+
+	gameVariables := func(gameID string) ([]*Variable, *Error) {
+		if cached, okay := variables[gameID]; okay {
+			return cached, nil
+		}
+
+		fetched, err := fetchVariables(request{"GET", "/games/" + gameID + "/variables", nil, nil, nil, ""})
+		if err != nil {
+			return nil, err
+		}
+
+		variables[gameID] = fetched.Variables()
+
+		return variables[gameID], nil
+	}
+
+	size := func(key LeaderboardKey) (int, *Error) {
+		if size, okay := sizes[key.String()]; okay {
+			return size, nil
+		}
+
+		lb, err := key.Fetch(nil, NoEmbeds)
+		if err != nil {
+			return 0, err
+		}
+
+		sizes[key.String()] = len(lb.Runs)
+
+		return len(lb.Runs), nil
+	}
+
+	return computeUserStats(userID, pbs.PersonalBests(), gameVariables, size)
+}
+
+// computeUserStats aggregates the PBs; variables must return the variables of
+// a game and size the number of runs on a leaderboard.
+func computeUserStats(userID string, pbs []*PersonalBest, variables func(string) ([]*Variable, *Error), size func(LeaderboardKey) (int, *Error)) (*UserStats, *Error) {
+	stats := &UserStats{UserID: userID}
+	games := make(map[string]bool)
+	categories := make(map[string]bool)
+	percentiles := 0.0
+	ranked := 0
+
+	var total time.Duration
+	var mostRecent time.Time
+
+	for _, pb := range pbs {
+		stats.PersonalBests++
+
+		if pb.Rank == 1 {
+			stats.WorldRecords++
+		}
+
+		if pb.Rank >= 1 && pb.Rank <= 3 {
+			stats.Podiums++
+		}
+
+		games[recastToID(pb.Run.GameData)] = true
+		categories[recastToID(pb.Run.CategoryData)] = true
+
+		if pb.Run.LevelData == nil {
+			stats.FullGame++
+		} else {
+			stats.IndividualLevel++
+		}
+
+		if pb.Run.Times.Primary != nil {
+			total += pb.Run.Times.Primary.Duration
+		}
+
+		if day := runDay(&pb.Run); stats.MostRecent == nil || day.After(mostRecent) {
+			stats.MostRecent = pb
+			mostRecent = day
+		}
+
+		if pb.Rank > 0 {
+			gameVariables, err := variables(recastToID(pb.Run.GameData))
+			if err != nil {
+				return nil, err
+			}
+
+			key := runLeaderboardKey(&pb.Run, gameVariables)
+
+			runs, err := size(key)
+			if err != nil {
+				return nil, err
+			}
+
+			if runs < pb.Rank {
+				return nil, &Error{"", "", ErrorBadLogic, "The leaderboard " + key.String() + " has fewer runs than the rank of the personal best on it."}
+			}
+
+			percentiles += 100 * float64(runs-pb.Rank+1) / float64(runs)
+			ranked++
+		}
+	}
+
+	stats.Games = len(games)
+	stats.Categories = len(categories)
+	stats.TotalTime = Duration{total}
+
+	if ranked > 0 {
+		stats.AveragePercentile = percentiles / float64(ranked)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUserStats(t *testing.T) {
+	// creates a PB in the given game, category and level (can be nil)
+	pb := func(rank int, game string, category string, level interface{}, day string, seconds int) *PersonalBest {
+		run := playedRun("r"+day, day, seconds, "me")
+		run.GameData = game
+		run.CategoryData = category
+		run.LevelData = level
+
+		return &PersonalBest{Rank: rank, Run: *run}
+	}
+
+	pbs := []*PersonalBest{
+		pb(1, "smw", "any", nil, "2015-01-01", 600),
+		pb(3, "smw", "96", nil, "2015-03-01", 3600),
+		pb(4, "smw", "il", "yoshi1", "2015-02-01", 30),
+		pb(10, "sm64", "120", nil, "2015-01-15", 6000),
+	}
+
+	// smw has a sub-category for the difficulty, with "normal" as the default
+	variables := func(gameID string) ([]*Variable, *Error) {
+		if gameID != "smw" {
+			return nil, nil
+		}
+
+		difficulty := &Variable{ID: "diff", IsSubcategory: true}
+		difficulty.Values.Choices = map[string]string{"normal": "Normal", "hard": "Hard"}
+		difficulty.Values.Default = "normal"
+
+		return []*Variable{difficulty, {ID: "emu"}}, nil
+	}
+
+	pbs[0].Run.Values = map[string]string{"diff": "normal", "emu": "yes"}
+	pbs[1].Run.Values = map[string]string{"diff": "hard"}
+
+	sizes := map[string]int{
+		"smw/any?var-diff=normal": 10,
+		"smw/any?var-diff=hard":   1,
+		"smw/96?var-diff=normal":  1,
+		"smw/96?var-diff=hard":    3,
+		"smw/il/yoshi1":           4,
+		"sm64/120":                20,
+	}
+
+	size := func(key LeaderboardKey) (int, *Error) {
+		return sizes[key.String()], nil
+	}
+
+	Convey("Aggregating PBs", t, func() {
+		stats, err := computeUserStats("me", pbs, variables, size)
+		So(err, ShouldBeNil)
+		So(stats.PersonalBests, ShouldEqual, 4)
+		So(stats.WorldRecords, ShouldEqual, 1)
+		So(stats.Podiums, ShouldEqual, 2)
+		So(stats.Games, ShouldEqual, 2)
+		So(stats.Categories, ShouldEqual, 4)
+		So(stats.FullGame, ShouldEqual, 3)
+		So(stats.IndividualLevel, ShouldEqual, 1)
+		So(stats.TotalTime.Duration, ShouldEqual, 10230*time.Second)
+		So(stats.MostRecent.Run.Times.Primary.Duration, ShouldEqual, time.Hour)
+
+		// (100 + 33.3 + 25 + 55) / 4
+		So(stats.AveragePercentile, ShouldAlmostEqual, 53.33, 0.01)
+	})
+
+	Convey("Aggregating nothing", t, func() {
+		stats, err := computeUserStats("me", nil, variables, size)
+		So(err, ShouldBeNil)
+		So(stats.PersonalBests, ShouldEqual, 0)
+		So(stats.MostRecent, ShouldBeNil)
+		So(stats.AveragePercentile, ShouldEqual, 0)
+	})
+	Convey("PBs ranked below the size of their leaderboard are reported", t, func() {
+		wrong := func(key LeaderboardKey) (int, *Error) {
+			return 1, nil
+		}
+
+		_, err := computeUserStats("me", pbs[1:2], variables, wrong)
+		So(err, ShouldNotBeNil)
+		So(err.Message, ShouldContainSubstring, "smw/96?var-diff=hard")
+	})
+}
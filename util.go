@@ -103,3 +103,88 @@ func isResponseLike(data map[string]interface{}) bool {
 
 	return isResponse
 }
+
+// searchSize determines the number of elements in a paginated collection by
+// probing single indices, so that only a logarithmic number of requests is
+// needed instead of fetching every page. known is the number of elements that
+// are known to exist; if limit is >0, it is the upper bound for the result.
+// probe must report whether the element with a given index exists; elements
+// that cannot be reached should be reported as missing, so that the result is
+// the number of reachable elements. The first error of probe is returned.
+func searchSize(known int, limit int, probe func(idx int) (bool, *Error)) (int, *Error) {
+	// there are at least lo elements; if hi is >0, there are less than hi
+	lo, hi := known, 0
+
+	if limit > 0 {
+		if lo >= limit {
+			return limit, nil
+		}
+
+		exists, err := probe(limit - 1)
+		if err != nil {
+			return -1, err
+		}
+
+		if exists {
+			return limit, nil
+		}
+
+		hi = limit
+	}
+
+	// find an upper bound by doubling the number of elements
+	for hi == 0 {
+		next := lo * 2
+		if next <= lo {
+			next = lo + 1
+		}
+
+		exists, err := probe(next - 1)
+		if err != nil {
+			return -1, err
+		}
+
+		if exists {
+			lo = next
+		} else {
+			hi = next
+		}
+	}
+
+	// narrow it down
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+
+		exists, err := probe(mid - 1)
+		if err != nil {
+			return -1, err
+		}
+
+		if exists {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// recastToID returns the ID of a related resource, regardless of whether it
+// has been embedded (then it looks like {"data": {"id": ...}}) or not (then it
+// is just the ID). An empty string is returned if there is no ID.
+func recastToID(data interface{}) string {
+	switch asserted := data.(type) {
+	case string:
+		return asserted
+
+	case map[string]interface{}:
+		if inner, okay := asserted["data"].(map[string]interface{}); okay {
+			if id, okay := inner["id"].(string); okay {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
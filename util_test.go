@@ -44,4 +44,55 @@ func TestUtils(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(tmp.Foo, ShouldEqual, "bar")
 	})
+
+	Convey("Test searchSize()", t, func() {
+		probes := 0
+		size := 0
+
+		probe := func(idx int) (bool, *Error) {
+			probes++
+			return idx < size, nil
+		}
+
+		for _, size = range []int{0, 1, 20, 21, 39, 40, 41, 1234, 50000} {
+			probes = 0
+
+			found, err := searchSize(0, 0, probe)
+			So(err, ShouldBeNil)
+			So(found, ShouldEqual, size)
+			So(probes, ShouldBeLessThan, 40)
+		}
+
+		size = 1234
+
+		found, _ := searchSize(20, 0, probe)
+		So(found, ShouldEqual, 1234)
+
+		found, _ = searchSize(20, 100, probe)
+		So(found, ShouldEqual, 100)
+
+		found, _ = searchSize(20, 5000, probe)
+		So(found, ShouldEqual, 1234)
+
+		// e.g. everything beyond MaxOffset
+		unreachable := func(idx int) (bool, *Error) {
+			return idx < 50, nil
+		}
+
+		found, err := searchSize(20, 0, unreachable)
+		So(err, ShouldBeNil)
+		So(found, ShouldEqual, 50)
+
+		failing := func(idx int) (bool, *Error) {
+			if idx >= 100 {
+				return false, &Error{"GET", "/runs", 503, "unavailable"}
+			}
+
+			return true, nil
+		}
+
+		found, err = searchSize(20, 0, failing)
+		So(err, ShouldNotBeNil)
+		So(found, ShouldEqual, -1)
+	})
 }
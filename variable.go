@@ -2,6 +2,8 @@
 
 package srapi
 
+import "strings"
+
 // Variable represents a variable.
 type Variable struct {
 	// `category` is not mapped on purpose, so we can have a Category()
@@ -14,10 +16,11 @@ type Variable struct {
 		Type  string
 		Level string
 	}
-	Mandatory   bool
-	UserDefined bool `json:"user-defined"`
-	Obsoletes   bool
-	Values      struct {
+	Mandatory     bool
+	UserDefined   bool `json:"user-defined"`
+	Obsoletes     bool
+	IsSubcategory bool `json:"is-subcategory"`
+	Values        struct {
 		Choices map[string]string
 		Default string
 	}
@@ -59,6 +62,40 @@ func (v *Variable) Category(embeds string) (*Category, *Error) {
 	return fetchCategoryLink(firstLink(v, "category"), embeds)
 }
 
+// categoryID returns the ID of the category the variable is restricted to,
+// taken from its API links. An empty string is returned if the variable
+// applies to all categories.
+func (v *Variable) categoryID() string {
+	link := firstLink(v, "category")
+	if link == nil {
+		return ""
+	}
+
+	return link.URI[strings.LastIndex(link.URI, "/")+1:]
+}
+
+// appliesTo checks if the variable is relevant for runs in the given category
+// and level (nil for full-game categories).
+func (v *Variable) appliesTo(cat *Category, level *Level) bool {
+	if catID := v.categoryID(); len(catID) > 0 && catID != cat.ID {
+		return false
+	}
+
+	switch v.Scope.Type {
+	case "full-game":
+		return level == nil
+
+	case "all-levels":
+		return level != nil
+
+	case "single-level":
+		return level != nil && v.Scope.Level == level.ID
+
+	default: // "global"
+		return true
+	}
+}
+
 // for the 'hasLinks' interface
 func (v *Variable) links() []Link {
 	return v.Links
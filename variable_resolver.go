@@ -0,0 +1,202 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"sync"
+)
+
+// LabeledValue is a variable value of a run or leaderboard, together with the
+// human-readable names of the variable and the value.
+type LabeledValue struct {
+	VariableID   string
+	VariableName string
+	ValueID      string
+	Label        string
+
+	// true if the variable or the chosen value does not exist anymore (e.g.
+	// because the value has been removed by a moderator); in this case, the
+	// names fall back to the IDs
+	Deleted bool
+}
+
+// VariableResolver turns variable and value IDs into their names. It keeps all
+// variables it ever saw, so when labelling many runs, re-using one resolver
+// means each game's variables are fetched at most once. Embedded variables
+// are used whenever available. A resolver is safe for concurrent use.
+type VariableResolver struct {
+	mutex     sync.Mutex
+	variables map[string]*Variable
+	games     map[string]bool
+}
+
+// NewVariableResolver creates an empty resolver.
+func NewVariableResolver() *VariableResolver {
+	return &VariableResolver{
+		variables: make(map[string]*Variable),
+		games:     make(map[string]bool),
+	}
+}
+
+// Add makes the variables known to the resolver.
+func (vr *VariableResolver) Add(variables *VariableCollection) {
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+
+	for idx := range variables.Data {
+		variable := variables.Data[idx]
+		vr.variables[variable.ID] = &variable
+	}
+}
+
+// Run returns the labelled values of a run.
+func (vr *VariableResolver) Run(r *Run) ([]LabeledValue, *Error) {
+	vr.addEmbedded(r.GameData, r.CategoryData)
+
+	return vr.resolve(recastToID(r.GameData), r.Values)
+}
+
+// PersonalBest returns the labelled values of a PB's run.
+func (vr *VariableResolver) PersonalBest(pb *PersonalBest) ([]LabeledValue, *Error) {
+	vr.addEmbedded(pb.GameData, pb.CategoryData, pb.Run.GameData, pb.Run.CategoryData)
+
+	gameID := recastToID(pb.GameData)
+	if len(gameID) == 0 {
+		gameID = recastToID(pb.Run.GameData)
+	}
+
+	return vr.resolve(gameID, pb.Run.Values)
+}
+
+// Leaderboard returns the labelled values the leaderboard has been filtered by.
+func (vr *VariableResolver) Leaderboard(lb *Leaderboard) ([]LabeledValue, *Error) {
+	if lb.VariablesData != nil {
+		vr.Add(lb.Variables())
+	}
+
+	vr.addEmbedded(lb.GameData, lb.CategoryData)
+
+	return vr.resolve(recastToID(lb.GameData), lb.Values)
+}
+
+// LabeledValues returns the run's variable values with their names. See
+// VariableResolver for labelling many runs efficiently.
+func (r *Run) LabeledValues() ([]LabeledValue, *Error) {
+	return NewVariableResolver().Run(r)
+}
+
+// LabeledValues returns the variable values of the PB's run with their names.
+// See VariableResolver for labelling many PBs efficiently.
+func (pb *PersonalBest) LabeledValues() ([]LabeledValue, *Error) {
+	return NewVariableResolver().PersonalBest(pb)
+}
+
+// LabeledValues returns the variable values the leaderboard has been filtered
+// by, together with their names.
+func (lb *Leaderboard) LabeledValues() ([]LabeledValue, *Error) {
+	return NewVariableResolver().Leaderboard(lb)
+}
+
+// addEmbedded collects the variables from embedded games and categories.
+// Anything else (like plain IDs) is ignored.
+func (vr *VariableResolver) addEmbedded(related ...interface{}) {
+	for _, data := range related {
+		if _, okay := data.(map[string]interface{}); !okay {
+			continue
+		}
+
+		// games and categories both have their variables in the same place
+		tmp := struct {
+			Data struct {
+				VariablesData interface{} `json:"variables"`
+			}
+		}{}
+
+		if recast(data, &tmp) == nil && tmp.Data.VariablesData != nil {
+			vr.Add(toVariableCollection(tmp.Data.VariablesData))
+		}
+	}
+}
+
+// resolve labels the values. If one of the variables is unknown, all
+// variables of the game are fetched in a single request (unless that already
+// happened before).
+func (vr *VariableResolver) resolve(gameID string, values map[string]string) ([]LabeledValue, *Error) {
+	if vr.missing(values) && len(gameID) > 0 && !vr.fetched(gameID) {
+		variables, err := fetchVariables(request{"GET", "/games/" + gameID + "/variables", nil, nil, nil, ""})
+		if err != nil {
+			return nil, err
+		}
+
+		vr.Add(variables)
+
+		vr.mutex.Lock()
+		vr.games[gameID] = true
+		vr.mutex.Unlock()
+	}
+
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+
+	var result []LabeledValue
+
+	for varID, valueID := range values {
+		labeled := LabeledValue{
+			VariableID:   varID,
+			VariableName: varID,
+			ValueID:      valueID,
+			Label:        valueID,
+			Deleted:      true,
+		}
+
+		if variable, exists := vr.variables[varID]; exists {
+			labeled.VariableName = variable.Name
+
+			if label, exists := variable.Values.Choices[valueID]; exists {
+				labeled.Label = label
+				labeled.Deleted = false
+			}
+		}
+
+		result = append(result, labeled)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].VariableName != result[j].VariableName {
+			return result[i].VariableName < result[j].VariableName
+		}
+
+		return result[i].VariableID < result[j].VariableID
+	})
+
+	return result, nil
+}
+
+// missing checks if any of the values belongs to an unknown variable or is an
+// unknown value.
+func (vr *VariableResolver) missing(values map[string]string) bool {
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+
+	for varID, valueID := range values {
+		variable, exists := vr.variables[varID]
+		if !exists {
+			return true
+		}
+
+		if _, exists := variable.Values.Choices[valueID]; !exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fetched checks if the variables of the game have already been fetched.
+func (vr *VariableResolver) fetched(gameID string) bool {
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+
+	return vr.games[gameID]
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVariableResolver(t *testing.T) {
+	difficulty := testVariable("diff", "", "global", "", true, "easy", "hard")
+	difficulty["name"] = "Difficulty"
+
+	version := testVariable("ver", "", "global", "", false, "jp")
+	version["name"] = "Version"
+
+	Convey("Labelling a run with embedded variables", t, func() {
+		run := &Run{
+			Values: map[string]string{"ver": "jp", "diff": "hard"},
+			CategoryData: map[string]interface{}{
+				"data": map[string]interface{}{"id": "any", "variables": embedded(difficulty, version)},
+			},
+		}
+
+		before := requestCount
+		labels, err := run.LabeledValues()
+		So(err, ShouldBeNil)
+		So(requestCount, ShouldEqual, before)
+
+		So(labels, ShouldResemble, []LabeledValue{
+			{"diff", "Difficulty", "hard", "Label hard", false},
+			{"ver", "Version", "jp", "Label jp", false},
+		})
+	})
+
+	Convey("Labelling a leaderboard with a removed value", t, func() {
+		lb := &Leaderboard{
+			Values:        map[string]string{"diff": "nightmare"},
+			VariablesData: embedded(difficulty),
+		}
+
+		// no game is known, so no request can be made
+		labels, err := lb.LabeledValues()
+		So(err, ShouldBeNil)
+		So(labels, ShouldResemble, []LabeledValue{
+			{"diff", "Difficulty", "nightmare", "nightmare", true},
+		})
+	})
+
+	Convey("Resolvers remember variables", t, func() {
+		resolver := NewVariableResolver()
+		resolver.Add(toVariableCollection(embedded(version)))
+
+		pb := &PersonalBest{Run: Run{Values: map[string]string{"ver": "jp"}}}
+
+		labels, err := resolver.PersonalBest(pb)
+		So(err, ShouldBeNil)
+		So(labels, ShouldHaveLength, 1)
+		So(labels[0].Label, ShouldEqual, "Label jp")
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// VerificationStats summarizes how a set of runs has been examined.
+type VerificationStats struct {
+	// number of verified runs
+	Verified int
+
+	// number of rejected runs
+	Rejected int
+
+	// number of runs that still wait for an examiner
+	Pending int
+
+	// median and 95th percentile of the time between submitting and examining
+	// a run; only runs that have both dates are considered (speedrun.com
+	// does not record a date for most rejections)
+	MedianWait time.Duration
+	P95Wait    time.Duration
+
+	// number of rejections per reason; rejections without a reason are
+	// counted under ""
+	RejectionReasons map[string]int
+}
+
+// BacklogPoint is the number of runs waiting for an examiner at a point in
+// time.
+type BacklogPoint struct {
+	Date    time.Time
+	Pending int
+}
+
+// VerificationReport aggregates the verification stats of a game or of an
+// examiner.
+type VerificationReport struct {
+	// the totals of all runs
+	VerificationStats
+
+	// the stats per examiner user ID (pending runs have no examiner and are
+	// only part of the totals)
+	ByExaminer map[string]*VerificationStats
+
+	// the stats per game ID
+	ByGame map[string]*VerificationStats
+
+	// the size of the backlog at each of the requested dates, oldest first
+	Backlog []BacklogPoint
+}
+
+// GameVerificationReport aggregates all runs of a game. The backlog is
+// computed for each of the given dates (see MonthlyDates()). As this looks at
+// every single run of the game, it can take many requests for popular games.
+func GameVerificationReport(gameID string, backlogDates []time.Time) (*VerificationReport, *Error) {
+	return fetchVerificationReport(&RunFilter{Game: gameID}, backlogDates)
+}
+
+// ExaminerVerificationReport aggregates all runs examined by a user, across
+// all games. As runs that are not examined yet have no examiner, Pending and
+// the backlog only include runs that the user examined later on.
+func ExaminerVerificationReport(userID string, backlogDates []time.Time) (*VerificationReport, *Error) {
+	return fetchVerificationReport(&RunFilter{Examiner: userID}, backlogDates)
+}
+
+// fetchVerificationReport walks all matching runs and aggregates them.
+func fetchVerificationReport(filter *RunFilter, backlogDates []time.Time) (*VerificationReport, *Error) {
+	var runs []*Run
+
+	err := WalkAllRuns(filter, NoEmbeds, func(run *Run) bool {
+		runs = append(runs, run)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newVerificationReport(runs, backlogDates), nil
+}
+
+// newVerificationReport aggregates the runs.
+func newVerificationReport(runs []*Run, backlogDates []time.Time) *VerificationReport {
+	byExaminer := make(map[string][]*Run)
+	byGame := make(map[string][]*Run)
+
+	for _, run := range runs {
+		if len(run.Status.Examiner) > 0 {
+			byExaminer[run.Status.Examiner] = append(byExaminer[run.Status.Examiner], run)
+		}
+
+		gameID := recastToID(run.GameData)
+		byGame[gameID] = append(byGame[gameID], run)
+	}
+
+	report := &VerificationReport{
+		VerificationStats: *verificationStats(runs),
+		ByExaminer:        make(map[string]*VerificationStats),
+		ByGame:            make(map[string]*VerificationStats),
+	}
+
+	for examiner, examined := range byExaminer {
+		report.ByExaminer[examiner] = verificationStats(examined)
+	}
+
+	for gameID, gameRuns := range byGame {
+		report.ByGame[gameID] = verificationStats(gameRuns)
+	}
+
+	dates := make([]time.Time, len(backlogDates))
+	copy(dates, backlogDates)
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	for _, date := range dates {
+		report.Backlog = append(report.Backlog, BacklogPoint{date, backlogAt(runs, date)})
+	}
+
+	return report
+}
+
+// verificationStats computes the stats for a set of runs.
+func verificationStats(runs []*Run) *VerificationStats {
+	stats := &VerificationStats{RejectionReasons: make(map[string]int)}
+
+	var waits []time.Duration
+
+	for _, run := range runs {
+		switch run.Status.Status {
+		case "verified":
+			stats.Verified++
+
+		case "rejected":
+			stats.Rejected++
+			stats.RejectionReasons[strings.TrimSpace(run.Status.Reason)]++
+
+		default:
+			stats.Pending++
+		}
+
+		if run.Submitted != nil && run.Status.VerifyDate != nil {
+			waits = append(waits, run.Status.VerifyDate.Sub(*run.Submitted))
+		}
+	}
+
+	if len(waits) > 0 {
+		sort.Slice(waits, func(i, j int) bool {
+			return waits[i] < waits[j]
+		})
+
+		median := waits[len(waits)/2]
+		if len(waits)%2 == 0 {
+			median = (waits[len(waits)/2-1] + median) / 2
+		}
+
+		// nearest-rank method
+		rank := (len(waits)*95 + 99) / 100
+
+		stats.MedianWait = median
+		stats.P95Wait = waits[rank-1]
+	}
+
+	return stats
+}
+
+// backlogAt counts the runs that were submitted, but not yet examined at the
+// given time. Runs that have been examined without a recorded date are
+// ignored, as it's unknown when they left the backlog.
+func backlogAt(runs []*Run, date time.Time) int {
+	pending := 0
+
+	for _, run := range runs {
+		if run.Submitted == nil || run.Submitted.After(date) {
+			continue
+		}
+
+		examined := run.Status.Status == "verified" || run.Status.Status == "rejected"
+
+		if !examined || (run.Status.VerifyDate != nil && run.Status.VerifyDate.After(date)) {
+			pending++
+		}
+	}
+
+	return pending
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVerificationReport(t *testing.T) {
+	base := time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// creates a run submitted on the given day, examined after the given
+	// number of hours (negative for no date)
+	examinedRun := func(id string, game string, status string, examiner string, day int, hours int, reason string) *Run {
+		submitted := base.AddDate(0, 0, day)
+
+		run := &Run{ID: id, Submitted: &submitted, GameData: game}
+		run.Status.Status = status
+		run.Status.Examiner = examiner
+		run.Status.Reason = reason
+
+		if hours >= 0 {
+			examined := submitted.Add(time.Duration(hours) * time.Hour)
+			run.Status.VerifyDate = &examined
+		}
+
+		return run
+	}
+
+	runs := []*Run{
+		examinedRun("a", "smw", "verified", "mod1", 0, 2, ""),
+		examinedRun("b", "smw", "verified", "mod1", 1, 4, ""),
+		examinedRun("c", "smw", "verified", "mod2", 2, 48, ""),
+		examinedRun("d", "smw", "rejected", "mod2", 3, -1, "No video "),
+		examinedRun("e", "sm64", "rejected", "mod2", 4, -1, "No video"),
+		examinedRun("f", "sm64", "rejected", "mod1", 4, 10, ""),
+		examinedRun("g", "sm64", "new", "", 5, -1, ""),
+	}
+
+	Convey("Aggregating runs", t, func() {
+		report := newVerificationReport(runs, nil)
+
+		So(report.Verified, ShouldEqual, 3)
+		So(report.Rejected, ShouldEqual, 3)
+		So(report.Pending, ShouldEqual, 1)
+		So(report.MedianWait, ShouldEqual, 7*time.Hour)
+		So(report.P95Wait, ShouldEqual, 48*time.Hour)
+		So(report.RejectionReasons, ShouldResemble, map[string]int{"No video": 2, "": 1})
+		So(report.Backlog, ShouldBeNil)
+
+		So(len(report.ByExaminer), ShouldEqual, 2)
+		So(report.ByExaminer["mod1"].Verified, ShouldEqual, 2)
+		So(report.ByExaminer["mod1"].Rejected, ShouldEqual, 1)
+		So(report.ByExaminer["mod1"].MedianWait, ShouldEqual, 4*time.Hour)
+		So(report.ByExaminer["mod2"].Rejected, ShouldEqual, 2)
+
+		So(report.ByGame["smw"].Verified, ShouldEqual, 3)
+		So(report.ByGame["sm64"].Pending, ShouldEqual, 1)
+	})
+
+	Convey("Computing the backlog", t, func() {
+		dates := []time.Time{base.AddDate(0, 0, 10), base.Add(3 * time.Hour), base.AddDate(0, 0, 3)}
+		report := newVerificationReport(runs, dates)
+
+		So(len(report.Backlog), ShouldEqual, 3)
+		So(report.Backlog[0].Date, ShouldResemble, dates[1])
+		So(report.Backlog[0].Pending, ShouldEqual, 0)
+
+		// only c (verified after two days) was waiting; d has no date
+		So(report.Backlog[1].Pending, ShouldEqual, 1)
+
+		So(report.Backlog[2].Pending, ShouldEqual, 1)
+	})
+}
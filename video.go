@@ -0,0 +1,245 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VideoHost specifies where a video is hosted and what kind of video it is.
+type VideoHost string
+
+const (
+	// YouTubeVideo is any video on YouTube.
+	YouTubeVideo VideoHost = "youtube"
+
+	// TwitchVOD is a past broadcast on Twitch.
+	TwitchVOD VideoHost = "twitch-vod"
+
+	// TwitchHighlight is a highlight on Twitch (using the old /c/ links).
+	TwitchHighlight VideoHost = "twitch-highlight"
+
+	// TwitchClip is a clip on Twitch.
+	TwitchClip VideoHost = "twitch-clip"
+
+	// OtherVideo is any link that is not recognized.
+	OtherVideo VideoHost = "other"
+)
+
+// Video is a parsed video link.
+type Video struct {
+	// the link as it was given
+	Original string
+
+	// the kind of video
+	Host VideoHost
+
+	// the video ID (or slug for Twitch clips), empty for OtherVideo
+	ID string
+
+	// the position the video starts at, if the link contains one
+	Timestamp time.Duration
+
+	// the normalized link; for known hosts, this is the canonical desktop link
+	// to the video (including the timestamp), for others the link with an
+	// explicit scheme
+	URL string
+}
+
+var (
+	youTubeID      = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	twitchID       = regexp.MustCompile(`^[0-9]+$`)
+	twitchClipSlug = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	videoTimestamp = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?$`)
+	textLink       = regexp.MustCompile(`(?i)\b(?:https?://|www\.|m\.|youtu\.be/|clips\.twitch\.tv/)[^\s<>"'\[\]]+`)
+)
+
+// ParseVideo classifies a video link. Links without a scheme, short links
+// (youtu.be) and mobile links (m.youtube.com, m.twitch.tv) are understood.
+// Links that cannot be parsed at all are returned as OtherVideo.
+func ParseVideo(link string) Video {
+	video := Video{Original: link, Host: OtherVideo, URL: strings.TrimSpace(link)}
+
+	raw := video.URL
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || len(u.Host) == 0 {
+		return video
+	}
+
+	video.URL = raw
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+	query := u.Query()
+
+	switch host {
+	case "youtube.com", "youtube-nocookie.com":
+		id := query.Get("v")
+
+		if len(path) == 2 {
+			switch path[0] {
+			case "embed", "v", "shorts", "live":
+				id = path[1]
+			}
+		}
+
+		video.setYouTube(id, u)
+
+	case "youtu.be":
+		video.setYouTube(path[0], u)
+
+	case "twitch.tv":
+		switch {
+		case len(path) == 2 && path[0] == "videos" && twitchID.MatchString(path[1]):
+			video.setTwitch(TwitchVOD, path[1], query.Get("t"))
+
+		case len(path) == 3 && (path[1] == "v" || path[1] == "b") && twitchID.MatchString(path[2]):
+			video.setTwitch(TwitchVOD, path[2], query.Get("t"))
+
+		case len(path) == 3 && path[1] == "c" && twitchID.MatchString(path[2]):
+			video.setTwitch(TwitchHighlight, path[2], query.Get("t"))
+
+		case len(path) == 3 && path[1] == "clip" && twitchClipSlug.MatchString(path[2]):
+			video.setTwitch(TwitchClip, path[2], "")
+		}
+
+	case "clips.twitch.tv":
+		if len(path) == 1 && twitchClipSlug.MatchString(path[0]) {
+			video.setTwitch(TwitchClip, path[0], "")
+		}
+	}
+
+	return video
+}
+
+// setYouTube turns the video into a YouTube video, if the ID is valid.
+func (v *Video) setYouTube(id string, u *url.URL) {
+	if !youTubeID.MatchString(id) {
+		return
+	}
+
+	query := u.Query()
+
+	v.Host = YouTubeVideo
+	v.ID = id
+	v.URL = "https://www.youtube.com/watch?v=" + id
+
+	stamp := query.Get("t")
+	if len(stamp) == 0 {
+		stamp = query.Get("start")
+	}
+
+	// youtu.be/ID#t=30 is an old, but still common form
+	if len(stamp) == 0 && strings.HasPrefix(u.Fragment, "t=") {
+		stamp = u.Fragment[2:]
+	}
+
+	v.Timestamp = parseVideoTimestamp(stamp)
+
+	if v.Timestamp > 0 {
+		v.URL += "&t=" + strconv.Itoa(int(v.Timestamp/time.Second)) + "s"
+	}
+}
+
+// setTwitch turns the video into a Twitch video.
+func (v *Video) setTwitch(host VideoHost, id string, stamp string) {
+	v.Host = host
+	v.ID = id
+	v.Timestamp = parseVideoTimestamp(stamp)
+
+	if host == TwitchClip {
+		v.URL = "https://clips.twitch.tv/" + id
+		return
+	}
+
+	v.URL = "https://www.twitch.tv/videos/" + id
+
+	if v.Timestamp > 0 {
+		v.URL += "?t=" + formatVideoTimestamp(v.Timestamp)
+	}
+}
+
+// parseVideoTimestamp parses timestamps like "90", "90s" or "1h2m3s". Invalid
+// timestamps are treated as zero.
+func parseVideoTimestamp(stamp string) time.Duration {
+	if len(stamp) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(stamp); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	match := videoTimestamp.FindStringSubmatch(strings.ToLower(stamp))
+	if match == nil {
+		return 0
+	}
+
+	result := time.Duration(0)
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+
+	for idx, unit := range units {
+		if value, err := strconv.Atoi(match[idx+1]); err == nil {
+			result += time.Duration(value) * unit
+		}
+	}
+
+	return result
+}
+
+// formatVideoTimestamp formats a timestamp as "1h2m3s", like Twitch does.
+func formatVideoTimestamp(stamp time.Duration) string {
+	seconds := int(stamp / time.Second)
+
+	return strconv.Itoa(seconds/3600) + "h" + strconv.Itoa(seconds/60%60) + "m" + strconv.Itoa(seconds%60) + "s"
+}
+
+// ParsedVideos returns the parsed video links of the run.
+func (r *Run) ParsedVideos() []Video {
+	var result []Video
+
+	for _, link := range r.Videos.Links {
+		result = append(result, ParseVideo(link.URI))
+	}
+
+	return result
+}
+
+// MissingVideos returns the video links that are part of the original
+// submission text, but are missing from the list of links. This happens
+// when speedrun.com failed to recognize a link. Links are compared after
+// normalizing them, so a short link in the text matches the full link in the
+// list.
+func (r *Run) MissingVideos() []Video {
+	known := make(map[string]bool)
+
+	for _, video := range r.ParsedVideos() {
+		known[video.URL] = true
+	}
+
+	var result []Video
+
+	for _, link := range textLink.FindAllString(r.Videos.Text, -1) {
+		link = strings.TrimRight(link, ".,;:!?)")
+		video := ParseVideo(link)
+
+		if !known[video.URL] {
+			known[video.URL] = true
+			result = append(result, video)
+		}
+	}
+
+	return result
+}
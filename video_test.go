@@ -0,0 +1,82 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVideos(t *testing.T) {
+	Convey("Parsing YouTube links", t, func() {
+		links := []string{
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			"http://youtube.com/watch?feature=share&v=dQw4w9WgXcQ",
+			"m.youtube.com/watch?v=dQw4w9WgXcQ",
+			"https://youtu.be/dQw4w9WgXcQ",
+			"https://www.youtube.com/embed/dQw4w9WgXcQ",
+		}
+
+		for _, link := range links {
+			video := ParseVideo(link)
+			So(video.Host, ShouldEqual, YouTubeVideo)
+			So(video.ID, ShouldEqual, "dQw4w9WgXcQ")
+			So(video.URL, ShouldEqual, "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+			So(video.Original, ShouldEqual, link)
+		}
+
+		video := ParseVideo("https://youtu.be/dQw4w9WgXcQ?t=1m30s")
+		So(video.Timestamp, ShouldEqual, 90*time.Second)
+		So(video.URL, ShouldEqual, "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=90s")
+
+		video = ParseVideo("https://www.youtube.com/watch?v=dQw4w9WgXcQ#t=42")
+		So(video.Timestamp, ShouldEqual, 42*time.Second)
+
+		So(ParseVideo("https://www.youtube.com/watch?v=short").Host, ShouldEqual, OtherVideo)
+	})
+
+	Convey("Parsing Twitch links", t, func() {
+		video := ParseVideo("https://www.twitch.tv/videos/123456?t=1h2m3s")
+		So(video.Host, ShouldEqual, TwitchVOD)
+		So(video.ID, ShouldEqual, "123456")
+		So(video.Timestamp, ShouldEqual, time.Hour+2*time.Minute+3*time.Second)
+		So(video.URL, ShouldEqual, "https://www.twitch.tv/videos/123456?t=1h2m3s")
+
+		video = ParseVideo("http://m.twitch.tv/someone/v/123456")
+		So(video.Host, ShouldEqual, TwitchVOD)
+		So(video.URL, ShouldEqual, "https://www.twitch.tv/videos/123456")
+
+		video = ParseVideo("twitch.tv/someone/c/987")
+		So(video.Host, ShouldEqual, TwitchHighlight)
+		So(video.ID, ShouldEqual, "987")
+
+		video = ParseVideo("https://www.twitch.tv/someone/clip/FunnyClipSlug")
+		So(video.Host, ShouldEqual, TwitchClip)
+		So(video.URL, ShouldEqual, "https://clips.twitch.tv/FunnyClipSlug")
+
+		So(ParseVideo("https://clips.twitch.tv/FunnyClipSlug").ID, ShouldEqual, "FunnyClipSlug")
+		So(ParseVideo("https://www.twitch.tv/someone").Host, ShouldEqual, OtherVideo)
+	})
+
+	Convey("Parsing other links", t, func() {
+		video := ParseVideo("vimeo.com/12345")
+		So(video.Host, ShouldEqual, OtherVideo)
+		So(video.ID, ShouldEqual, "")
+		So(video.URL, ShouldEqual, "https://vimeo.com/12345")
+	})
+
+	Convey("Finding missing links", t, func() {
+		run := &Run{}
+		run.Videos.Text = "Run: youtu.be/dQw4w9WgXcQ, splits at https://www.twitch.tv/videos/42. Program.exe"
+		run.Videos.Links = []Link{{URI: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}}
+
+		missing := run.MissingVideos()
+		So(len(missing), ShouldEqual, 1)
+		So(missing[0].Host, ShouldEqual, TwitchVOD)
+		So(missing[0].ID, ShouldEqual, "42")
+
+		So(len(run.ParsedVideos()), ShouldEqual, 1)
+	})
+}
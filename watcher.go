@@ -0,0 +1,546 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// EventType identifies what a watcher has noticed.
+type EventType string
+
+const (
+	// RunSubmitted is emitted for runs that have been submitted and wait for
+	// an examiner.
+	RunSubmitted EventType = "run-submitted"
+
+	// RunVerified is emitted for runs that have been verified.
+	RunVerified EventType = "run-verified"
+
+	// RunRejected is emitted for runs that have been rejected.
+	RunRejected EventType = "run-rejected"
+
+	// NewWorldRecord is emitted when a run takes the first place of a
+	// watched leaderboard with a time faster than the previous record.
+	NewWorldRecord EventType = "new-world-record"
+
+	// NewPersonalBest is emitted when a run appears on a watched leaderboard,
+	// without beating the record.
+	NewPersonalBest EventType = "new-personal-best"
+
+	// PollFailed is emitted when polling failed; the watcher keeps going and
+	// tries again after the interval.
+	PollFailed EventType = "poll-failed"
+)
+
+// Event is something a watcher has noticed.
+type Event struct {
+	Type EventType
+
+	// the time the event was noticed, according to the watcher's clock
+	Time time.Time
+
+	// the run the event is about, nil for PollFailed
+	Run *Run
+
+	// for leaderboard events, the current leaderboard and the run's rank on it
+	Leaderboard *Leaderboard
+	Rank        int
+
+	// for PollFailed, what went wrong
+	Error *Error
+}
+
+// Clock tells the time and waits. It can be replaced in watchers, e.g. in
+// tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock using the real time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Watcher polls runs and leaderboards and emits events for everything new.
+// What has been seen is kept in a StateStore, so a watcher that is restarted
+// with the same store continues where it stopped. On the very first poll of
+// a run filter or leaderboard, the current state is only remembered and no
+// events are emitted, so starting a watcher does not flood the consumer.
+//
+// For runs, the first page of verified runs (newest verifications first) is
+// polled. Unverified runs are polled newest submission first, page by page,
+// until all runs that were waiting at the last poll have been seen again or
+// the pages have gone past the oldest of them. Runs that left the list of
+// unverified runs without showing up as verified are fetched individually to
+// find out whether they were rejected. A run that is submitted and verified
+// between two polls only causes a RunVerified event.
+//
+// For leaderboards, each poll is compared to the previous one (see
+// DiffLeaderboards()). A run that beats the previous record causes a
+// NewWorldRecord event, every other new run on the leaderboard (including one
+// that ties the record) causes a NewPersonalBest event.
+type Watcher struct {
+	interval     time.Duration
+	store        StateStore
+	clock        Clock
+	runs         []watchedRuns
+	leaderboards []watchedLeaderboard
+
+	events     chan Event
+	killSwitch chan struct{}
+	done       chan struct{}
+
+	// data sources, replaced in tests
+	pageSize         int
+	fetchRuns        func(filter *RunFilter, sort *Sorting, cursor *Cursor, embeds string) ([]*Run, *Error)
+	fetchRun         func(id string, embeds string) (*Run, *Error)
+	fetchLeaderboard func(key LeaderboardKey, options *LeaderboardOptions, embeds string) (*Leaderboard, *Error)
+}
+
+// watchedRuns is a run filter registered with WatchRuns().
+type watchedRuns struct {
+	filter RunFilter
+	embeds string
+}
+
+// watchedLeaderboard is a leaderboard registered with WatchLeaderboard().
+type watchedLeaderboard struct {
+	key     LeaderboardKey
+	options *LeaderboardOptions
+	embeds  string
+}
+
+// runWatchState is what a watcher remembers about a run filter.
+type runWatchState struct {
+	// IDs of runs that were waiting for an examiner at the last poll
+	Pending map[string]bool `json:"pending"`
+
+	// the submission date of the oldest of those runs
+	Oldest time.Time `json:"oldest"`
+
+	// the latest verification date seen so far
+	Verified time.Time `json:"verified"`
+
+	// IDs of runs that were verified exactly at Verified
+	VerifiedIDs map[string]bool `json:"verified-ids"`
+}
+
+// NewWatcher creates a watcher that polls every interval and remembers its
+// state in the store. Register what to watch with WatchRuns() and
+// WatchLeaderboard(), then call Start().
+func NewWatcher(store StateStore, interval time.Duration) *Watcher {
+	return &Watcher{
+		interval: interval,
+		store:    store,
+		clock:    systemClock{},
+
+		pageSize: maxPageSize,
+
+		fetchRuns: func(filter *RunFilter, sort *Sorting, cursor *Cursor, embeds string) ([]*Run, *Error) {
+			runs, err := Runs(filter, sort, cursor, embeds)
+			if err != nil {
+				return nil, err
+			}
+
+			// only this one page, never the following ones
+			var result []*Run
+
+			for idx := range runs.Data {
+				result = append(result, &runs.Data[idx])
+			}
+
+			return result, nil
+		},
+
+		fetchRun: RunByID,
+
+		fetchLeaderboard: func(key LeaderboardKey, options *LeaderboardOptions, embeds string) (*Leaderboard, *Error) {
+			return key.Fetch(options, embeds)
+		},
+	}
+}
+
+// SetClock replaces the clock used for timestamps and for waiting between
+// polls. It must be called before Start().
+func (w *Watcher) SetClock(clock Clock) {
+	w.clock = clock
+}
+
+// WatchRuns makes the watcher poll runs matching the filter (e.g. all runs
+// of a game). The filter's Status is ignored. embeds are applied to the runs
+// in the events.
+func (w *Watcher) WatchRuns(filter *RunFilter, embeds string) {
+	watched := watchedRuns{embeds: embeds}
+
+	if filter != nil {
+		watched.filter = *filter
+	}
+
+	watched.filter.Status = ""
+	w.runs = append(w.runs, watched)
+}
+
+// WatchLeaderboard makes the watcher poll a leaderboard. The options and
+// embeds are used for fetching it.
+func (w *Watcher) WatchLeaderboard(key LeaderboardKey, options *LeaderboardOptions, embeds string) {
+	w.leaderboards = append(w.leaderboards, watchedLeaderboard{key, options, embeds})
+}
+
+// Start begins polling in the background, starting right away. The returned
+// channel receives all events and is closed after Stop() has been called.
+// Start must only be called once.
+func (w *Watcher) Start() <-chan Event {
+	w.events = make(chan Event)
+	w.killSwitch = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go w.work()
+
+	return w.events
+}
+
+// Stop ends polling. It waits for the current poll to finish.
+func (w *Watcher) Stop() {
+	close(w.killSwitch)
+	<-w.done
+}
+
+// Poll checks everything that is watched once and returns the events, in the
+// order in which they happened. If some of the runs or leaderboards could not
+// be polled, the events of the others are returned together with the last
+// error; the failed ones are tried again on the next poll.
+func (w *Watcher) Poll() ([]Event, *Error) {
+	var result []Event
+	var lastErr *Error
+
+	for _, watched := range w.runs {
+		events, err := w.pollRuns(watched)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		result = append(result, events...)
+	}
+
+	for _, watched := range w.leaderboards {
+		events, err := w.pollLeaderboard(watched)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		result = append(result, events...)
+	}
+
+	return result, lastErr
+}
+
+// work is the goroutine that polls until the watcher is stopped.
+func (w *Watcher) work() {
+	defer close(w.done)
+	defer close(w.events)
+
+	for {
+		events, err := w.Poll()
+
+		if err != nil {
+			events = append(events, Event{Type: PollFailed, Time: w.clock.Now(), Error: err})
+		}
+
+		for _, event := range events {
+			select {
+			case <-w.killSwitch:
+				return
+
+			case w.events <- event:
+			}
+		}
+
+		select {
+		case <-w.killSwitch:
+			return
+
+		case <-w.clock.After(w.interval):
+		}
+	}
+}
+
+// pollRuns looks for submitted, verified and rejected runs.
+func (w *Watcher) pollRuns(watched watchedRuns) ([]Event, *Error) {
+	u := &url.URL{}
+	watched.filter.applyToURL(u)
+
+	stateKey := "runs?" + u.RawQuery
+	state := runWatchState{}
+
+	known, err := w.load(stateKey, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	if !known || state.Pending == nil || state.VerifiedIDs == nil {
+		state = runWatchState{Pending: make(map[string]bool), VerifiedIDs: make(map[string]bool)}
+	}
+
+	filter := watched.filter
+	filter.Status = "verified"
+
+	verified, err := w.fetchRuns(&filter, &Sorting{"verify-date", Descending}, &Cursor{0, w.pageSize}, watched.embeds)
+	if err != nil {
+		return nil, err
+	}
+
+	now := w.clock.Now()
+	var events []Event
+
+	// oldest verification first
+	for idx := len(verified) - 1; idx >= 0; idx-- {
+		run := verified[idx]
+
+		if state.newlyVerified(run) {
+			state.markVerified(run)
+			delete(state.Pending, run.ID)
+
+			events = append(events, Event{Type: RunVerified, Time: now, Run: run})
+		}
+	}
+
+	// verified runs are out of the way now, so only runs that really left the
+	// list of unverified runs make pendingRuns() look further
+	filter.Status = "new"
+
+	pending, err := w.pendingRuns(&filter, &state, !known, watched.embeds)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool)
+	oldest := time.Time{}
+
+	waiting := func(run *Run) {
+		current[run.ID] = true
+
+		if run.Submitted != nil && (oldest.IsZero() || run.Submitted.Before(oldest)) {
+			oldest = *run.Submitted
+		}
+	}
+
+	// oldest submission first
+	for idx := len(pending) - 1; idx >= 0; idx-- {
+		run := pending[idx]
+		waiting(run)
+
+		if !state.Pending[run.ID] {
+			events = append(events, Event{Type: RunSubmitted, Time: now, Run: run})
+		}
+	}
+
+	// find out what happened to runs that are not waiting anymore
+	var left []string
+
+	for runID := range state.Pending {
+		if !current[runID] {
+			left = append(left, runID)
+		}
+	}
+
+	sort.Strings(left)
+
+	for _, runID := range left {
+		run, err := w.fetchRun(runID, watched.embeds)
+		if err != nil {
+			// the run has been deleted
+			if err.Status == 404 {
+				continue
+			}
+
+			return nil, err
+		}
+
+		switch run.Status.Status {
+		case "verified":
+			// verified after the verified runs were fetched; the next poll
+			// will see it there again
+			state.markVerified(run)
+			events = append(events, Event{Type: RunVerified, Time: now, Run: run})
+
+		case "rejected":
+			events = append(events, Event{Type: RunRejected, Time: now, Run: run})
+
+		default:
+			// still waiting, but too far down the list (see MaxOffset)
+			waiting(run)
+		}
+	}
+
+	state.Pending = current
+	state.Oldest = oldest
+
+	if err := w.save(stateKey, state); err != nil {
+		return nil, err
+	}
+
+	if !known {
+		return nil, nil
+	}
+
+	return events, nil
+}
+
+// pendingRuns fetches the unverified runs, newest submission first. Pages are
+// fetched until every run of state.Pending has been seen, a page goes past
+// state.Oldest or there are no more runs, so runs missing from the result
+// are not waiting anymore. For the baseline, all pages are fetched.
+func (w *Watcher) pendingRuns(filter *RunFilter, state *runWatchState, baseline bool, embeds string) ([]*Run, *Error) {
+	var result []*Run
+	seen := 0
+
+	for offset := 0; offset < MaxOffset; offset += w.pageSize {
+		page, err := w.fetchRuns(filter, &Sorting{"submitted", Descending}, &Cursor{offset, w.pageSize}, embeds)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, page...)
+
+		for _, run := range page {
+			if state.Pending[run.ID] {
+				seen++
+			}
+		}
+
+		if len(page) < w.pageSize || (!baseline && seen >= len(state.Pending)) {
+			break
+		}
+
+		last := page[len(page)-1]
+		if !baseline && !state.Oldest.IsZero() && last.Submitted != nil && last.Submitted.Before(state.Oldest) {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// pollLeaderboard looks for new records and PBs on a leaderboard.
+func (w *Watcher) pollLeaderboard(watched watchedLeaderboard) ([]Event, *Error) {
+	u := &url.URL{}
+	watched.options.applyToURL(u)
+
+	stateKey := "leaderboard:" + watched.key.String() + "|" + u.RawQuery
+	previous := &Leaderboard{}
+
+	known, err := w.load(stateKey, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := w.fetchLeaderboard(watched.key, watched.options, watched.embeds)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.save(stateKey, current); err != nil {
+		return nil, err
+	}
+
+	if !known {
+		return nil, nil
+	}
+
+	diff, err := DiffLeaderboards(previous, current)
+	if err != nil {
+		// the stored leaderboard is for something else; start over
+		return nil, nil
+	}
+
+	now := w.clock.Now()
+	records := make(map[string]bool)
+	var events []Event
+
+	for idx := range diff.NewRecords {
+		ranked := diff.NewRecords[idx]
+		records[ranked.Run.ID] = true
+
+		events = append(events, Event{Type: NewWorldRecord, Time: now, Run: &ranked.Run, Leaderboard: current, Rank: ranked.Rank})
+	}
+
+	for idx := range diff.Added {
+		ranked := diff.Added[idx]
+
+		if !records[ranked.Run.ID] {
+			events = append(events, Event{Type: NewPersonalBest, Time: now, Run: &ranked.Run, Leaderboard: current, Rank: ranked.Rank})
+		}
+	}
+
+	return events, nil
+}
+
+// load decodes a value from the store. It returns false if there is none.
+func (w *Watcher) load(key string, dst interface{}) (bool, *Error) {
+	encoded, err := w.store.Load(key)
+	if err != nil {
+		return false, &Error{"", "", ErrorStateStore, err.Error()}
+	}
+
+	if encoded == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(encoded, dst); err != nil {
+		return false, &Error{"", "", ErrorStateStore, "Could not decode the stored state: " + err.Error()}
+	}
+
+	return true, nil
+}
+
+// save encodes a value and puts it into the store.
+func (w *Watcher) save(key string, value interface{}) *Error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return &Error{"", "", ErrorStateStore, "Could not encode the state: " + err.Error()}
+	}
+
+	if err := w.store.Save(key, encoded); err != nil {
+		return &Error{"", "", ErrorStateStore, err.Error()}
+	}
+
+	return nil
+}
+
+// newlyVerified checks if a run has been verified after the last poll.
+func (s *runWatchState) newlyVerified(run *Run) bool {
+	date := run.Status.VerifyDate
+	if date == nil {
+		return false
+	}
+
+	return date.After(s.Verified) || (date.Equal(s.Verified) && !s.VerifiedIDs[run.ID])
+}
+
+// markVerified moves the verification high-water mark.
+func (s *runWatchState) markVerified(run *Run) {
+	date := run.Status.VerifyDate
+	if date == nil {
+		return
+	}
+
+	if date.After(s.Verified) {
+		s.Verified = *date
+		s.VerifiedIDs = make(map[string]bool)
+	}
+
+	s.VerifiedIDs[run.ID] = true
+}
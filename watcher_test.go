@@ -0,0 +1,318 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeClock is a Clock whose time only moves when the test says so.
+type fakeClock struct {
+	now     time.Time
+	waiting chan struct{}
+	ticks   chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2015, 8, 1, 12, 0, 0, 0, time.UTC),
+		waiting: make(chan struct{}, 1),
+		ticks:   make(chan time.Time),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waiting <- struct{}{}
+	return c.ticks
+}
+
+// fakeRunSource serves runs to a watcher instead of the API.
+type fakeRunSource struct {
+	mutex sync.Mutex
+	runs  map[string]*Run
+	order []string
+
+	// number of requests for single runs
+	gets int
+}
+
+func (s *fakeRunSource) set(id string, status string, verified int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.runs == nil {
+		s.runs = make(map[string]*Run)
+	}
+
+	if _, exists := s.runs[id]; !exists {
+		s.order = append(s.order, id)
+	}
+
+	submitted := time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC)
+	for idx, other := range s.order {
+		if other == id {
+			submitted = submitted.Add(time.Duration(idx) * time.Minute)
+		}
+	}
+
+	run := &Run{ID: id, Submitted: &submitted}
+	run.Status.Status = status
+
+	if verified > 0 {
+		date := time.Date(2015, 8, 1, verified, 0, 0, 0, time.UTC)
+		run.Status.VerifyDate = &date
+	}
+
+	s.runs[id] = run
+}
+
+func (s *fakeRunSource) list(filter *RunFilter, sorting *Sorting, cursor *Cursor, embeds string) ([]*Run, *Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result []*Run
+
+	// newest first
+	for idx := len(s.order) - 1; idx >= 0; idx-- {
+		if run := s.runs[s.order[idx]]; run.Status.Status == filter.Status {
+			result = append(result, run)
+		}
+	}
+
+	if filter.Status == "verified" {
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Status.VerifyDate.After(*result[j].Status.VerifyDate)
+		})
+	}
+
+	if cursor.Offset >= len(result) {
+		return nil, nil
+	}
+
+	result = result[cursor.Offset:]
+	if len(result) > cursor.Max {
+		result = result[:cursor.Max]
+	}
+
+	return result, nil
+}
+
+func (s *fakeRunSource) get(id string, embeds string) (*Run, *Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.gets++
+
+	if run, exists := s.runs[id]; exists {
+		return run, nil
+	}
+
+	return nil, &Error{"GET", "/runs/" + id, 404, "not found"}
+}
+
+func TestWatcher(t *testing.T) {
+	types := func(events []Event) []string {
+		var result []string
+
+		for _, event := range events {
+			result = append(result, string(event.Type)+":"+event.Run.ID)
+		}
+
+		return result
+	}
+
+	newTestWatcher := func(source *fakeRunSource, store StateStore) *Watcher {
+		w := NewWatcher(store, time.Minute)
+		w.fetchRuns = source.list
+		w.fetchRun = source.get
+		w.WatchRuns(&RunFilter{Game: "smw"}, NoEmbeds)
+
+		return w
+	}
+
+	Convey("Watching runs", t, func() {
+		source := &fakeRunSource{}
+		source.set("old", "verified", 1)
+		source.set("a", "new", 0)
+		source.set("b", "new", 0)
+
+		store := NewMemoryStore()
+		w := newTestWatcher(source, store)
+
+		events, err := w.Poll()
+		So(err, ShouldBeNil)
+		So(events, ShouldBeNil)
+
+		source.set("c", "new", 0)
+		source.set("a", "verified", 3)
+		source.set("b", "rejected", 0)
+
+		events, err = w.Poll()
+		So(err, ShouldBeNil)
+		So(types(events), ShouldResemble, []string{"run-verified:a", "run-submitted:c", "run-rejected:b"})
+
+		events, err = w.Poll()
+		So(err, ShouldBeNil)
+		So(events, ShouldBeNil)
+
+		Convey("A new watcher continues where the old one stopped", func() {
+			source.set("c", "verified", 3)
+			source.set("d", "new", 0)
+
+			events, err := newTestWatcher(source, store).Poll()
+			So(err, ShouldBeNil)
+			So(types(events), ShouldResemble, []string{"run-verified:c", "run-submitted:d"})
+		})
+	})
+
+	Convey("Watching more unverified runs than fit on a page", t, func() {
+		source := &fakeRunSource{}
+		for _, id := range []string{"a", "b", "c", "d", "e"} {
+			source.set(id, "new", 0)
+		}
+
+		w := newTestWatcher(source, NewMemoryStore())
+		w.pageSize = 2
+
+		_, err := w.Poll()
+		So(err, ShouldBeNil)
+
+		// a, b and c are further down the list now
+		source.set("f", "new", 0)
+		source.set("g", "new", 0)
+		source.set("h", "new", 0)
+		source.set("d", "rejected", 0)
+
+		events, err := w.Poll()
+		So(err, ShouldBeNil)
+		So(types(events), ShouldResemble, []string{"run-submitted:f", "run-submitted:g", "run-submitted:h", "run-rejected:d"})
+		So(source.gets, ShouldEqual, 1)
+
+		events, err = w.Poll()
+		So(err, ShouldBeNil)
+		So(events, ShouldBeNil)
+		So(source.gets, ShouldEqual, 1)
+	})
+
+	Convey("Runs verified while polling are reported once", t, func() {
+		source := &fakeRunSource{}
+		source.set("a", "new", 0)
+
+		w := newTestWatcher(source, NewMemoryStore())
+
+		_, err := w.Poll()
+		So(err, ShouldBeNil)
+
+		// a is verified right after the verified runs have been fetched
+		w.fetchRuns = func(filter *RunFilter, sorting *Sorting, cursor *Cursor, embeds string) ([]*Run, *Error) {
+			runs, err := source.list(filter, sorting, cursor, embeds)
+
+			if filter.Status == "verified" {
+				source.set("a", "verified", 3)
+			}
+
+			return runs, err
+		}
+
+		events, err := w.Poll()
+		So(err, ShouldBeNil)
+		So(types(events), ShouldResemble, []string{"run-verified:a"})
+
+		w.fetchRuns = source.list
+
+		events, err = w.Poll()
+		So(err, ShouldBeNil)
+		So(events, ShouldBeNil)
+	})
+
+	Convey("Watching leaderboards", t, func() {
+		boards := []*Leaderboard{
+			decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "runs": [
+				{"place": 1, "run": {"id": "a", "times": {"primary_t": 10}}},
+				{"place": 2, "run": {"id": "b", "times": {"primary_t": 20}}}
+			]}`),
+			decodeLeaderboard(`{"game": "om1m3625", "category": "w20p0zkn", "runs": [
+				{"place": 1, "run": {"id": "c", "times": {"primary_t": 5}}},
+				{"place": 2, "run": {"id": "a", "times": {"primary_t": 10}}},
+				{"place": 3, "run": {"id": "d", "times": {"primary_t": 15}}},
+				{"place": 4, "run": {"id": "b", "times": {"primary_t": 20}}}
+			]}`),
+		}
+
+		w := NewWatcher(NewMemoryStore(), time.Minute)
+		w.fetchLeaderboard = func(key LeaderboardKey, options *LeaderboardOptions, embeds string) (*Leaderboard, *Error) {
+			lb := boards[0]
+			boards = boards[1:]
+
+			return lb, nil
+		}
+
+		w.WatchLeaderboard(boards[0].Key(), nil, NoEmbeds)
+
+		events, err := w.Poll()
+		So(err, ShouldBeNil)
+		So(events, ShouldBeNil)
+
+		events, err = w.Poll()
+		So(err, ShouldBeNil)
+		So(types(events), ShouldResemble, []string{"new-world-record:c", "new-personal-best:d"})
+		So(events[1].Rank, ShouldEqual, 3)
+		So(events[1].Leaderboard.Runs[0].Run.ID, ShouldEqual, "c")
+	})
+
+	Convey("Running a watcher in the background", t, func() {
+		source := &fakeRunSource{}
+		clock := newFakeClock()
+
+		w := newTestWatcher(source, NewMemoryStore())
+		w.SetClock(clock)
+
+		events := w.Start()
+		<-clock.waiting
+
+		source.set("a", "new", 0)
+		clock.ticks <- clock.now
+
+		event := <-events
+		So(event.Type, ShouldEqual, RunSubmitted)
+		So(event.Run.ID, ShouldEqual, "a")
+		So(event.Time, ShouldResemble, clock.now)
+
+		<-clock.waiting
+		w.Stop()
+
+		_, open := <-events
+		So(open, ShouldBeFalse)
+	})
+
+	Convey("Storing state in a file", t, func() {
+		dir, err := ioutil.TempDir("", "srapi")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "state.json")
+
+		value, err := NewFileStore(path).Load("key")
+		So(err, ShouldBeNil)
+		So(value, ShouldBeNil)
+
+		So(NewFileStore(path).Save("key", []byte(`{"a":1}`)), ShouldBeNil)
+		So(NewFileStore(path).Save("other", []byte(`2`)), ShouldBeNil)
+
+		value, err = NewFileStore(path).Load("key")
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, `{"a":1}`)
+	})
+}
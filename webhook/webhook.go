@@ -0,0 +1,336 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+// Package webhook delivers watcher events to HTTP endpoints. Each event is
+// POSTed as a JSON document; deliveries that fail even after retrying are
+// written to a dead-letter file, so they can be inspected or replayed.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+)
+
+// SignatureHeader is the HTTP header containing the HMAC-SHA256 signature of
+// the request body, formatted as "sha256=<hex digest>".
+const SignatureHeader = "X-Srapi-Signature"
+
+// EventHeader is the HTTP header containing the event type.
+const EventHeader = "X-Srapi-Event"
+
+// Endpoint is a receiver of events.
+type Endpoint struct {
+	// the URL to POST to
+	URL string
+
+	// if not empty, requests are signed with this key
+	Secret string
+
+	// if not empty, only events for these game IDs are sent
+	Games []string
+
+	// if not empty, only events for these category IDs are sent
+	Categories []string
+
+	// if not empty, only these types of events are sent
+	Types []srapi.EventType
+}
+
+// Ref is a game or category in a payload. The name and weblink are only
+// available if the game/category has been embedded in the run.
+type Ref struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Weblink string `json:"weblink,omitempty"`
+}
+
+// PlayerRef is a player in a payload. For users, ID is set; the name is only
+// available for guests and if the players have been embedded in the run.
+type PlayerRef struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Payload is the JSON document that is sent for an event.
+type Payload struct {
+	Event    srapi.EventType `json:"event"`
+	Time     time.Time       `json:"time"`
+	Run      *srapi.Run      `json:"run"`
+	Game     Ref             `json:"game"`
+	Category Ref             `json:"category"`
+	Players  []PlayerRef     `json:"players"`
+	Rank     int             `json:"rank,omitempty"`
+}
+
+// Dispatcher sends events to endpoints. Watchers should embed "game,category,
+// players" into runs, so payloads contain names without further requests.
+type Dispatcher struct {
+	// how often a delivery is attempted before giving up (default 5)
+	Attempts int
+
+	// the wait before the first retry; it doubles with every further retry
+	// (default 1s)
+	Backoff time.Duration
+
+	endpoints  []Endpoint
+	deadLetter string
+	client     *http.Client
+	mutex      sync.Mutex
+
+	// replaced in tests
+	sleep func(time.Duration)
+}
+
+// deadLetter is a line in the dead-letter file.
+type deadLetter struct {
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	Error    string    `json:"error"`
+	Payload  *Payload  `json:"payload"`
+}
+
+// NewDispatcher creates a dispatcher for the endpoints. Failed deliveries are
+// appended to deadLetterFile as JSON lines; if it is empty, they are dropped.
+func NewDispatcher(endpoints []Endpoint, deadLetterFile string) *Dispatcher {
+	return &Dispatcher{
+		Attempts:   5,
+		Backoff:    time.Second,
+		endpoints:  endpoints,
+		deadLetter: deadLetterFile,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		sleep:      time.Sleep,
+	}
+}
+
+// Run dispatches all events from the channel until it is closed, e.g. the
+// channel returned by Watcher.Start().
+func (d *Dispatcher) Run(events <-chan srapi.Event) {
+	for event := range events {
+		d.Dispatch(event)
+	}
+}
+
+// Dispatch sends the event to all matching endpoints, one after another.
+// Events without a run (like PollFailed) are ignored. The error of the last
+// failed delivery is returned, if any.
+func (d *Dispatcher) Dispatch(event srapi.Event) *srapi.Error {
+	if event.Run == nil {
+		return nil
+	}
+
+	payload := NewPayload(event)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return &srapi.Error{Status: srapi.ErrorBadJSON, Message: err.Error()}
+	}
+
+	var lastErr *srapi.Error
+
+	for _, endpoint := range d.endpoints {
+		if !endpoint.matches(payload) {
+			continue
+		}
+
+		if failure := d.deliver(endpoint, payload, body); failure != nil {
+			lastErr = failure
+			d.bury(endpoint, payload, failure)
+		}
+	}
+
+	return lastErr
+}
+
+// NewPayload assembles the payload for an event. No requests are performed.
+func NewPayload(event srapi.Event) *Payload {
+	run := event.Run
+
+	payload := &Payload{
+		Event:    event.Type,
+		Time:     event.Time,
+		Run:      run,
+		Game:     Ref{ID: run.GameID()},
+		Category: Ref{ID: run.CategoryID()},
+		Players:  []PlayerRef{},
+		Rank:     event.Rank,
+	}
+
+	// only look at embedded data, so that no requests are made
+	if _, embedded := run.GameData.(map[string]interface{}); embedded {
+		if game, err := run.Game(srapi.NoEmbeds); err == nil && game != nil {
+			payload.Game = Ref{game.ID, game.Names.International, game.Weblink}
+		}
+	}
+
+	if _, embedded := run.CategoryData.(map[string]interface{}); embedded {
+		if cat, err := run.Category(srapi.NoEmbeds); err == nil && cat != nil {
+			payload.Category = Ref{cat.ID, cat.Name, cat.Weblink}
+		}
+	}
+
+	if _, embedded := run.PlayersData.(map[string]interface{}); embedded {
+		players, _ := run.Players()
+
+		for _, player := range players.Players() {
+			ref := PlayerRef{Name: player.Name()}
+			if player.User != nil {
+				ref.ID = player.User.ID
+			}
+
+			payload.Players = append(payload.Players, ref)
+		}
+	} else {
+		links, _ := run.PlayerLinks()
+
+		for _, link := range links {
+			payload.Players = append(payload.Players, PlayerRef{link.ID, link.Name})
+		}
+	}
+
+	return payload
+}
+
+// Sign computes the signature of a request body, as sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a request body, for use in receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// matches checks the endpoint's filters.
+func (e *Endpoint) matches(payload *Payload) bool {
+	if !contains(e.Games, payload.Game.ID) || !contains(e.Categories, payload.Category.ID) {
+		return false
+	}
+
+	if len(e.Types) == 0 {
+		return true
+	}
+
+	for _, eventType := range e.Types {
+		if eventType == payload.Event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliver POSTs the body, retrying on network errors, server errors and rate
+// limiting.
+func (d *Dispatcher) deliver(endpoint Endpoint, payload *Payload, body []byte) *srapi.Error {
+	wait := d.Backoff
+	attempts := d.Attempts
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var failure *srapi.Error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			d.sleep(wait)
+			wait *= 2
+		}
+
+		var retry bool
+
+		failure, retry = d.post(endpoint, payload, body)
+		if failure == nil || !retry {
+			return failure
+		}
+	}
+
+	return failure
+}
+
+// post performs a single delivery attempt. It reports whether a failed
+// attempt is worth retrying.
+func (d *Dispatcher) post(endpoint Endpoint, payload *Payload, body []byte) (*srapi.Error, bool) {
+	req, err := http.NewRequest("POST", endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return &srapi.Error{Method: "POST", URL: endpoint.URL, Status: srapi.ErrorBadURL, Message: err.Error()}, false
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-srapi/"+srapi.Version)
+	req.Header.Set(EventHeader, string(payload.Event))
+
+	if len(endpoint.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, body))
+	}
+
+	response, err := d.client.Do(req)
+	if err != nil {
+		return &srapi.Error{Method: "POST", URL: endpoint.URL, Status: srapi.ErrorNetwork, Message: err.Error()}, true
+	}
+
+	// drain the body so that the connection can be re-used
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil, false
+	}
+
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+
+	return &srapi.Error{Method: "POST", URL: endpoint.URL, Status: response.StatusCode, Message: "The endpoint responded with " + response.Status + "."}, retry
+}
+
+// bury appends a failed delivery to the dead-letter file.
+func (d *Dispatcher) bury(endpoint Endpoint, payload *Payload, failure *srapi.Error) {
+	if len(d.deadLetter) == 0 {
+		return
+	}
+
+	line, err := json.Marshal(deadLetter{time.Now(), endpoint.URL, failure.Error(), payload})
+	if err != nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	file, err := os.OpenFile(d.deadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	file.Write(append(line, '\n'))
+}
+
+// contains checks if the ID is in the list; an empty list contains
+// everything.
+func contains(list []string, id string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, item := range list {
+		if item == id {
+			return true
+		}
+	}
+
+	return false
+}
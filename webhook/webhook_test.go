@@ -0,0 +1,169 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sgt-kabukiman/srapi"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// receiver is a local endpoint that records what it receives and fails the
+// first few requests.
+type receiver struct {
+	mutex    sync.Mutex
+	failures int
+	status   int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(req.Body)
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header)
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(r.status)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testEvent(gameID string) srapi.Event {
+	run := &srapi.Run{ID: "run1", GameData: gameID}
+	run.CategoryData = map[string]interface{}{"data": map[string]interface{}{"id": "any", "name": "Any%"}}
+	run.PlayersData = []interface{}{
+		map[string]interface{}{"rel": "user", "id": "u1", "uri": srapi.BaseURL + "/users/u1"},
+		map[string]interface{}{"rel": "guest", "name": "someone", "uri": srapi.BaseURL + "/guests/someone"},
+	}
+
+	return srapi.Event{Type: srapi.NewPersonalBest, Time: time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC), Run: run, Rank: 3}
+}
+
+func TestDispatcher(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(dir)
+
+	var sleeps []time.Duration
+
+	newDispatcher := func(endpoints ...Endpoint) *Dispatcher {
+		sleeps = nil
+
+		d := NewDispatcher(endpoints, filepath.Join(dir, "dead.jsonl"))
+		d.Attempts = 3
+		d.sleep = func(wait time.Duration) {
+			sleeps = append(sleeps, wait)
+		}
+
+		return d
+	}
+
+	Convey("Delivering signed payloads", t, func() {
+		r := &receiver{}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(Endpoint{URL: server.URL, Secret: "s3cr3t"})
+		So(d.Dispatch(testEvent("smw")), ShouldBeNil)
+
+		So(len(r.bodies), ShouldEqual, 1)
+		So(r.headers[0].Get(EventHeader), ShouldEqual, "new-personal-best")
+		So(Verify("s3cr3t", r.bodies[0], r.headers[0].Get(SignatureHeader)), ShouldBeTrue)
+		So(Verify("wrong", r.bodies[0], r.headers[0].Get(SignatureHeader)), ShouldBeFalse)
+
+		payload := Payload{}
+		So(json.Unmarshal(r.bodies[0], &payload), ShouldBeNil)
+		So(payload.Event, ShouldEqual, srapi.NewPersonalBest)
+		So(payload.Run.ID, ShouldEqual, "run1")
+		So(payload.Game, ShouldResemble, Ref{ID: "smw"})
+		So(payload.Category, ShouldResemble, Ref{ID: "any", Name: "Any%"})
+		So(payload.Players, ShouldResemble, []PlayerRef{{ID: "u1"}, {Name: "someone"}})
+		So(payload.Rank, ShouldEqual, 3)
+	})
+
+	Convey("Filtering events per endpoint", t, func() {
+		r := &receiver{}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(
+			Endpoint{URL: server.URL + "/smw", Games: []string{"smw"}},
+			Endpoint{URL: server.URL + "/sm64", Games: []string{"sm64"}},
+			Endpoint{URL: server.URL + "/wr", Types: []srapi.EventType{srapi.NewWorldRecord}},
+			Endpoint{URL: server.URL + "/any", Categories: []string{"any"}},
+		)
+
+		So(d.Dispatch(testEvent("smw")), ShouldBeNil)
+		So(len(r.bodies), ShouldEqual, 2)
+
+		So(d.Dispatch(srapi.Event{Type: srapi.PollFailed}), ShouldBeNil)
+		So(len(r.bodies), ShouldEqual, 2)
+	})
+
+	Convey("Retrying failed deliveries", t, func() {
+		r := &receiver{failures: 2, status: http.StatusServiceUnavailable}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(Endpoint{URL: server.URL})
+		d.Backoff = time.Second
+
+		So(d.Dispatch(testEvent("smw")), ShouldBeNil)
+		So(len(r.bodies), ShouldEqual, 3)
+		So(sleeps, ShouldResemble, []time.Duration{time.Second, 2 * time.Second})
+	})
+
+	Convey("Giving up on deliveries", t, func() {
+		r := &receiver{failures: 10, status: http.StatusBadRequest}
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		d := newDispatcher(Endpoint{URL: server.URL})
+
+		err := d.Dispatch(testEvent("smw"))
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, http.StatusBadRequest)
+
+		// client errors are not retried
+		So(len(r.bodies), ShouldEqual, 1)
+
+		r.status = http.StatusInternalServerError
+		So(d.Dispatch(testEvent("sm64")), ShouldNotBeNil)
+		So(len(r.bodies), ShouldEqual, 4)
+
+		file, err2 := os.Open(filepath.Join(dir, "dead.jsonl"))
+		So(err2, ShouldBeNil)
+		defer file.Close()
+
+		var letters []deadLetter
+		scanner := bufio.NewScanner(file)
+
+		for scanner.Scan() {
+			letter := deadLetter{}
+			So(json.Unmarshal(scanner.Bytes(), &letter), ShouldBeNil)
+			letters = append(letters, letter)
+		}
+
+		So(len(letters), ShouldEqual, 2)
+		So(letters[0].Endpoint, ShouldEqual, server.URL)
+		So(letters[1].Payload.Game.ID, ShouldEqual, "sm64")
+		So(letters[1].Error, ShouldContainSubstring, "500")
+	})
+}
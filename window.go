@@ -0,0 +1,405 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+// MaxOffset is the largest offset the speedrun.com API accepts when paging
+// through a collection. Regular iterators simply stop once they reach it; the
+// WalkAll* functions work around it by splitting the query.
+const MaxOffset = 10000
+
+// windowPager fetches a single page of a query, sorted in the given direction,
+// and returns the IDs of the items on it, a function to hand the item with a
+// given index to the caller and whether or not there are more pages.
+type windowPager func(dir Direction, cursor *Cursor) (ids []string, emit func(idx int) bool, more bool, err *Error)
+
+// walkWindows walks through a query from both ends. It first pages through it
+// in ascending order until either the end or limit (the offset limit, usually
+// MaxOffset) is reached and then
+// continues in descending order until it hits an item that has already been
+// seen in the ascending window. Items in seen are never emitted twice. done is
+// false if the query could not be enumerated completely this way. stopped is
+// true if the caller's walker function asked to stop.
+func walkWindows(pager windowPager, limit int, seen map[string]bool) (done bool, stopped bool, err *Error) {
+	ascending := make(map[string]bool)
+
+	done, stopped, err = walkWindow(pager, Ascending, limit, seen, ascending)
+	if done || stopped || err != nil {
+		return
+	}
+
+	return walkWindow(pager, Descending, limit, seen, ascending)
+}
+
+// walkWindow pages through a query in one direction, skipping all items that
+// have been seen before and recording the IDs of all items in window. When
+// walking in descending order, reaching an item from the (ascending) window
+// means both have met and the query is done; the rest of that page is still
+// consumed to cope with items that share the same sorting key and come in a
+// different order.
+func walkWindow(pager windowPager, dir Direction, limit int, seen map[string]bool, window map[string]bool) (bool, bool, *Error) {
+	for offset := 0; offset < limit; {
+		ids, emit, more, err := pager(dir, &Cursor{offset, maxPageSize})
+		if err != nil {
+			return false, false, err
+		}
+
+		met := false
+
+		for idx, id := range ids {
+			if dir == Ascending {
+				window[id] = true
+			} else if window[id] {
+				met = true
+			}
+
+			if seen[id] {
+				continue
+			}
+
+			seen[id] = true
+
+			if !emit(idx) {
+				return true, true, nil
+			}
+		}
+
+		if met || !more || len(ids) == 0 {
+			return true, false, nil
+		}
+
+		offset += len(ids)
+	}
+
+	return false, false, nil
+}
+
+// WalkAllRuns applies a function to every run matching the filter. In contrast
+// to Runs(f, ...).Walk(...), it is not limited by MaxOffset: Runs are sorted by
+// their submission date and fetched from both ends; if that is still not
+// enough, the filter is split (by category, level, status and emulator usage)
+// into smaller queries that are enumerated one after another. Runs are only
+// ever handed to the walker once, even if they show up in multiple windows.
+// Note that the order of runs is not defined. If the walker returns false,
+// walking stops. If the query cannot be split any further, an error with
+// status ErrorOffsetLimit is returned after all reachable runs have been
+// walked.
+func WalkAllRuns(f *RunFilter, embeds string, walker RunWalkerFunc) *Error {
+	filter := RunFilter{}
+	if f != nil {
+		filter = *f
+	}
+
+	w := &runWalk{pages: Runs, limit: MaxOffset, embeds: embeds, walker: walker, seen: make(map[string]bool)}
+	_, err := w.walk(filter)
+
+	return err
+}
+
+// runWalk is a walk through all runs of a query, handing each run only once
+// to the walker.
+type runWalk struct {
+	// fetches a page of runs, usually Runs
+	pages func(filter *RunFilter, sort *Sorting, cursor *Cursor, embeds string) (*RunCollection, *Error)
+
+	// the offset limit of pages, usually MaxOffset
+	limit int
+
+	embeds string
+	walker RunWalkerFunc
+	seen   map[string]bool
+}
+
+// walk enumerates a single filter and recursively splits it if needed.
+func (w *runWalk) walk(filter RunFilter) (bool, *Error) {
+	pager := func(dir Direction, cursor *Cursor) ([]string, func(int) bool, bool, *Error) {
+		runs, err := w.pages(&filter, &Sorting{"submitted", dir}, cursor, w.embeds)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		ids := make([]string, len(runs.Data))
+		for idx, run := range runs.Data {
+			ids[idx] = run.ID
+		}
+
+		emit := func(idx int) bool {
+			return w.walker(&runs.Data[idx])
+		}
+
+		return ids, emit, firstLink(&runs.Pagination, "next") != nil, nil
+	}
+
+	done, stopped, err := walkWindows(pager, w.limit, w.seen)
+	if done || stopped || err != nil {
+		return stopped, err
+	}
+
+	parts, err := splitRunFilter(filter)
+	if err != nil {
+		return false, err
+	}
+
+	if len(parts) == 0 {
+		return false, &Error{"", "", ErrorOffsetLimit, "Too many runs for the given filter and it cannot be split any further."}
+	}
+
+	return walkParts(len(parts), func(idx int) (bool, *Error) {
+		return w.walk(parts[idx])
+	})
+}
+
+// walkParts walks the parts of a split query one after another. A part that
+// hits the offset limit does not keep the other parts from being walked; the
+// error is returned after all parts are done. Walking only stops early if the
+// walker asks to or on any other error.
+func walkParts(parts int, walk func(idx int) (bool, *Error)) (bool, *Error) {
+	var limitErr *Error
+
+	for idx := 0; idx < parts; idx++ {
+		stopped, err := walk(idx)
+		if stopped {
+			return true, nil
+		}
+
+		if err != nil {
+			if err.Status != ErrorOffsetLimit {
+				return false, err
+			}
+
+			limitErr = err
+		}
+	}
+
+	return false, limitErr
+}
+
+// splitRunFilter splits a filter into multiple disjoint filters that together
+// match the same runs. An empty list is returned if there is no way to split
+// the filter.
+func splitRunFilter(filter RunFilter) ([]RunFilter, *Error) {
+	var result []RunFilter
+
+	// every run belongs to exactly one category
+	if len(filter.Game) > 0 && len(filter.Category) == 0 {
+		game, err := GameByID(filter.Game, "categories")
+		if err != nil {
+			return nil, err
+		}
+
+		categories, err := game.Categories(nil, nil, NoEmbeds)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, category := range categories.Categories() {
+			part := filter
+			part.Category = category.ID
+
+			result = append(result, part)
+		}
+
+		return result, nil
+	}
+
+	// individual-level runs can be split by their level
+	if len(filter.Category) > 0 && len(filter.Level) == 0 {
+		category, err := CategoryByID(filter.Category, "game")
+		if err != nil {
+			return nil, err
+		}
+
+		if category.Type == "per-level" {
+			game, err := category.Game("levels")
+			if err != nil {
+				return nil, err
+			}
+
+			levels, err := game.Levels(nil, NoEmbeds)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, level := range levels.Levels() {
+				part := filter
+				part.Level = level.ID
+
+				result = append(result, part)
+			}
+
+			return result, nil
+		}
+	}
+
+	if len(filter.Status) == 0 {
+		for _, status := range []string{"new", "verified", "rejected"} {
+			part := filter
+			part.Status = status
+
+			result = append(result, part)
+		}
+
+		return result, nil
+	}
+
+	if filter.Emulated == Undefined {
+		for _, flag := range []OptionalFlag{Yes, No} {
+			part := filter
+			part.Emulated = flag
+
+			result = append(result, part)
+		}
+	}
+
+	return result, nil
+}
+
+// WalkAllGames applies a function to every game matching the filter, working
+// around MaxOffset like WalkAllRuns does. Games are sorted by their creation
+// date and fetched from both ends; if that is not sufficient, the filter is
+// split into romhacks and regular games and then by release year; games
+// without a release year are reached by sorting by release year, as they come
+// first then. The order of games is not defined.
+// If the walker returns false, walking stops. If the query cannot be split any
+// further, an error with status ErrorOffsetLimit is returned after all
+// reachable games have been walked.
+func WalkAllGames(f *GameFilter, embeds string, walker GameWalkerFunc) *Error {
+	filter := GameFilter{}
+	if f != nil {
+		filter = *f
+	}
+
+	w := &gameWalk{pages: Games, limit: MaxOffset, embeds: embeds, walker: walker, seen: make(map[string]bool)}
+	_, err := w.walk(filter)
+
+	return err
+}
+
+// gameWalk is a walk through all games of a query, handing each game only
+// once to the walker.
+type gameWalk struct {
+	// fetches a page of games, usually Games
+	pages func(filter *GameFilter, sort *Sorting, cursor *Cursor, embeds string) (*GameCollection, *Error)
+
+	// the offset limit of pages, usually MaxOffset
+	limit int
+
+	embeds string
+	walker GameWalkerFunc
+	seen   map[string]bool
+}
+
+// walk enumerates a single filter and recursively splits it if needed.
+func (w *gameWalk) walk(filter GameFilter) (bool, *Error) {
+	pager := func(dir Direction, cursor *Cursor) ([]string, func(int) bool, bool, *Error) {
+		games, err := w.pages(&filter, &Sorting{"created", dir}, cursor, w.embeds)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		ids := make([]string, len(games.Data))
+		for idx, game := range games.Data {
+			ids[idx] = game.ID
+		}
+
+		emit := func(idx int) bool {
+			return w.walker(&games.Data[idx])
+		}
+
+		return ids, emit, firstLink(&games.Pagination, "next") != nil, nil
+	}
+
+	done, stopped, err := walkWindows(pager, w.limit, w.seen)
+	if done || stopped || err != nil {
+		return stopped, err
+	}
+
+	// split into romhacks and regular games first
+	if filter.Romhack == Undefined {
+		parts := []OptionalFlag{No, Yes}
+
+		return walkParts(len(parts), func(idx int) (bool, *Error) {
+			part := filter
+			part.Romhack = parts[idx]
+
+			return w.walk(part)
+		})
+	}
+
+	if filter.Released > 0 {
+		return false, &Error{"", "", ErrorOffsetLimit, "Too many games for the given filter and it cannot be split any further."}
+	}
+
+	return w.walkYears(filter)
+}
+
+// walkYears splits a filter by release year. Games without a release year
+// cannot be filtered for, so they are walked first, which also yields the
+// oldest release year. If there are too many of them, the range of years is
+// unknown and an error with status ErrorOffsetLimit is returned.
+func (w *gameWalk) walkYears(filter GameFilter) (bool, *Error) {
+	stopped, from, err := w.walkUnreleased(filter)
+	if stopped || err != nil {
+		return stopped, err
+	}
+
+	// all games are unreleased and have been walked
+	if from == 0 {
+		return false, nil
+	}
+
+	newest, err := w.pages(&filter, &Sorting{"released", Descending}, &Cursor{0, 1}, NoEmbeds)
+	if err != nil {
+		return false, err
+	}
+
+	to := from
+	if len(newest.Data) > 0 && newest.Data[0].Released > to {
+		to = newest.Data[0].Released
+	}
+
+	return walkParts(to-from+1, func(idx int) (bool, *Error) {
+		part := filter
+		part.Released = from + idx
+
+		return w.walk(part)
+	})
+}
+
+// walkUnreleased walks the games without a release year, which come first
+// when sorting by release year, and returns the release year of the first
+// game after them (0 if there is none). An error with status ErrorOffsetLimit
+// is returned if there are too many unreleased games to reach them all.
+func (w *gameWalk) walkUnreleased(filter GameFilter) (bool, int, *Error) {
+	for offset := 0; offset < w.limit; {
+		games, err := w.pages(&filter, &Sorting{"released", Ascending}, &Cursor{offset, maxPageSize}, w.embeds)
+		if err != nil {
+			return false, 0, err
+		}
+
+		for idx := range games.Data {
+			game := &games.Data[idx]
+
+			if game.Released > 0 {
+				return false, game.Released, nil
+			}
+
+			if w.seen[game.ID] {
+				continue
+			}
+
+			w.seen[game.ID] = true
+
+			if !w.walker(game) {
+				return true, 0, nil
+			}
+		}
+
+		if len(games.Data) == 0 || firstLink(&games.Pagination, "next") == nil {
+			return false, 0, nil
+		}
+
+		offset += len(games.Data)
+	}
+
+	return false, 0, &Error{"", "", ErrorOffsetLimit, "Too many games without a release year for the given filter."}
+}
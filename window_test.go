@@ -0,0 +1,302 @@
+// Copyright (c) 2015, Sgt. Kabukiman | MIT licensed
+
+package srapi
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakePager simulates a sorted query over n items with IDs "0" to "n-1".
+func fakePager(n int) windowPager {
+	return func(dir Direction, cursor *Cursor) ([]string, func(int) bool, bool, *Error) {
+		var ids []string
+
+		for idx := cursor.Offset; idx < cursor.Offset+cursor.Max && idx < n; idx++ {
+			item := idx
+			if dir == Descending {
+				item = n - 1 - idx
+			}
+
+			ids = append(ids, strconv.Itoa(item))
+		}
+
+		more := cursor.Offset+len(ids) < n
+		emit := func(int) bool { return true }
+
+		return ids, emit, more, nil
+	}
+}
+
+// fakePageSize is the number of items per page of the fake sources.
+const fakePageSize = 2
+
+// fakePage cuts a page out of a sorted list of items and tells if there are
+// more items after it.
+func fakePage(total int, cursor *Cursor) (from int, to int, pagination Pagination) {
+	from, to = cursor.Offset, cursor.Offset+fakePageSize
+	if from > total {
+		from = total
+	}
+
+	if to > total {
+		to = total
+	}
+
+	if to < total {
+		pagination.Links = []Link{{Relation: "next", URI: BaseURL + "/next"}}
+	}
+
+	return from, to, pagination
+}
+
+// matches checks if a value matches an optional flag.
+func matches(flag OptionalFlag, value bool) bool {
+	return flag == Undefined || (flag == Yes) == value
+}
+
+// fakeRuns serves the given runs instead of the API, filtered by status and
+// emulator usage.
+func fakeRuns(runs []Run) func(*RunFilter, *Sorting, *Cursor, string) (*RunCollection, *Error) {
+	return func(filter *RunFilter, sorting *Sorting, cursor *Cursor, embeds string) (*RunCollection, *Error) {
+		var matching []Run
+
+		for _, run := range runs {
+			if (len(filter.Status) == 0 || run.Status.Status == filter.Status) && matches(filter.Emulated, run.System.Emulated) {
+				matching = append(matching, run)
+			}
+		}
+
+		sort.SliceStable(matching, func(i, j int) bool {
+			if sorting.Direction == Descending {
+				return matching[i].Submitted.After(*matching[j].Submitted)
+			}
+
+			return matching[i].Submitted.Before(*matching[j].Submitted)
+		})
+
+		from, to, pagination := fakePage(len(matching), cursor)
+
+		return &RunCollection{Data: matching[from:to], Pagination: pagination}, nil
+	}
+}
+
+// fakeGames serves the given games instead of the API, filtered by romhack
+// and release year.
+func fakeGames(games []Game) func(*GameFilter, *Sorting, *Cursor, string) (*GameCollection, *Error) {
+	return func(filter *GameFilter, sorting *Sorting, cursor *Cursor, embeds string) (*GameCollection, *Error) {
+		var matching []Game
+
+		for _, game := range games {
+			if matches(filter.Romhack, game.Romhack) && (filter.Released == 0 || game.Released == filter.Released) {
+				matching = append(matching, game)
+			}
+		}
+
+		sort.SliceStable(matching, func(i, j int) bool {
+			a, b := matching[i], matching[j]
+			if sorting.Direction == Descending {
+				a, b = b, a
+			}
+
+			if sorting.OrderBy == "released" {
+				return a.Released < b.Released
+			}
+
+			return a.Created.Before(*b.Created)
+		})
+
+		from, to, pagination := fakePage(len(matching), cursor)
+
+		return &GameCollection{Data: matching[from:to], Pagination: pagination}, nil
+	}
+}
+
+func TestWindows(t *testing.T) {
+	const limit = 1000
+
+	Convey("Small queries are done within the first window", t, func() {
+		seen := make(map[string]bool)
+
+		done, stopped, err := walkWindows(fakePager(700), limit, seen)
+		So(err, ShouldBeNil)
+		So(done, ShouldBeTrue)
+		So(stopped, ShouldBeFalse)
+		So(seen, ShouldHaveLength, 700)
+	})
+
+	Convey("Larger queries are completed from the other end", t, func() {
+		seen := make(map[string]bool)
+
+		done, _, err := walkWindows(fakePager(1500), limit, seen)
+		So(err, ShouldBeNil)
+		So(done, ShouldBeTrue)
+		So(seen, ShouldHaveLength, 1500)
+	})
+
+	Convey("Huge queries cannot be completed", t, func() {
+		seen := make(map[string]bool)
+
+		done, _, err := walkWindows(fakePager(2500), limit, seen)
+		So(err, ShouldBeNil)
+		So(done, ShouldBeFalse)
+		So(seen, ShouldHaveLength, 2000)
+	})
+
+	Convey("Items seen in other queries are skipped, but do not end a window", t, func() {
+		seen := map[string]bool{"1499": true, "1498": true}
+		emitted := 0
+
+		pager := fakePager(1500)
+		counting := func(dir Direction, cursor *Cursor) ([]string, func(int) bool, bool, *Error) {
+			ids, _, more, err := pager(dir, cursor)
+			return ids, func(int) bool { emitted++; return true }, more, err
+		}
+
+		done, _, err := walkWindows(counting, limit, seen)
+		So(err, ShouldBeNil)
+		So(done, ShouldBeTrue)
+		So(emitted, ShouldEqual, 1498)
+	})
+}
+
+func TestWalkAll(t *testing.T) {
+	// queries can reach 4 items from either end
+	const limit = 4
+
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var runs []Run
+
+	addRuns := func(n int, status string, emulated bool) {
+		for idx := 0; idx < n; idx++ {
+			submitted := start.Add(time.Duration(len(runs)) * time.Hour)
+
+			run := Run{ID: strconv.Itoa(len(runs)), Submitted: &submitted}
+			run.Status.Status = status
+			run.System.Emulated = emulated
+
+			runs = append(runs, run)
+		}
+	}
+
+	// too many new runs, even after splitting them by emulator usage; the
+	// verified runs fit after splitting, the rejected runs without
+	addRuns(10, "new", false)
+	addRuns(6, "verified", false)
+	addRuns(6, "verified", true)
+	addRuns(3, "rejected", false)
+
+	walkRuns := func(walker RunWalkerFunc) *Error {
+		w := &runWalk{pages: fakeRuns(runs), limit: limit, walker: walker, seen: make(map[string]bool)}
+		_, err := w.walk(RunFilter{})
+
+		return err
+	}
+
+	Convey("Walking runs splits the filter and skips unreachable parts", t, func() {
+		walked := make(map[string]bool)
+
+		err := walkRuns(func(run *Run) bool {
+			So(walked[run.ID], ShouldBeFalse)
+			walked[run.ID] = true
+
+			return true
+		})
+
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, ErrorOffsetLimit)
+
+		// all but 2 of the new runs
+		So(walked, ShouldHaveLength, 23)
+		So(walked["24"], ShouldBeTrue)
+	})
+
+	Convey("Walking runs stops when the walker asks to", t, func() {
+		walked := 0
+
+		err := walkRuns(func(run *Run) bool {
+			walked++
+			return walked < 12
+		})
+
+		So(err, ShouldBeNil)
+		So(walked, ShouldEqual, 12)
+	})
+
+	var games []Game
+
+	addGames := func(n int, released int, romhack bool) {
+		for idx := 0; idx < n; idx++ {
+			created := start.Add(time.Duration(len(games)) * time.Hour)
+			games = append(games, Game{ID: strconv.Itoa(len(games)), Released: released, Romhack: romhack, Created: &created})
+		}
+	}
+
+	// the unreleased games cannot be reached from either end
+	addGames(7, 2003, false)
+	addGames(7, 2001, false)
+	addGames(3, 0, false)
+	addGames(7, 2002, false)
+	addGames(3, 2002, true)
+
+	walkGames := func(walker GameWalkerFunc) *Error {
+		w := &gameWalk{pages: fakeGames(games), limit: limit, walker: walker, seen: make(map[string]bool)}
+		_, err := w.walk(GameFilter{})
+
+		return err
+	}
+
+	Convey("Walking games splits by romhack and release year", t, func() {
+		walked := make(map[string]bool)
+
+		err := walkGames(func(game *Game) bool {
+			walked[game.ID] = true
+			return true
+		})
+
+		So(err, ShouldBeNil)
+		So(walked, ShouldHaveLength, 27)
+		So(walked["14"], ShouldBeTrue)
+	})
+
+	Convey("Walking games reports years that are too large", t, func() {
+		addGames(10, 2004, false)
+
+		walked := 0
+
+		err := walkGames(func(game *Game) bool {
+			walked++
+			return true
+		})
+
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, ErrorOffsetLimit)
+		So(walked, ShouldEqual, 35)
+	})
+
+	Convey("Walking games reports too many unreleased games", t, func() {
+		var unreleased []Game
+
+		for idx := 0; idx < 10; idx++ {
+			created := start.Add(time.Duration(idx) * time.Hour)
+			unreleased = append(unreleased, Game{ID: strconv.Itoa(idx), Created: &created})
+		}
+
+		walked := 0
+
+		w := &gameWalk{pages: fakeGames(unreleased), limit: limit, seen: make(map[string]bool), walker: func(game *Game) bool {
+			walked++
+			return true
+		}}
+
+		_, err := w.walk(GameFilter{})
+		So(err, ShouldNotBeNil)
+		So(err.Status, ShouldEqual, ErrorOffsetLimit)
+		So(walked, ShouldEqual, 8)
+	})
+}